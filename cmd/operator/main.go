@@ -27,11 +27,30 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
+// Environment variables holding the images deployed by the operator. They
+// follow the convention used by OLM for images referenced by an operator.
+const (
+	prometheusImageEnv   = "RELATED_IMAGE_PROMETHEUS"
+	alertmanagerImageEnv = "RELATED_IMAGE_ALERTMANAGER"
+	thanosImageEnv       = "RELATED_IMAGE_THANOS"
+)
+
+func envOrDefault(name, defaultValue string) string {
+	if v, ok := os.LookupEnv(name); ok && v != "" {
+		return v
+	}
+	return defaultValue
+}
+
 func main() {
 	var (
-		namespace       string
-		metricsAddr     string
-		healthProbeAddr string
+		namespace         string
+		metricsAddr       string
+		healthProbeAddr   string
+		prometheusImage   string
+		alertmanagerImage string
+		thanosImage       string
+		webhookCertDir    string
 
 		setupLog = ctrl.Log.WithName("setup")
 	)
@@ -39,6 +58,14 @@ func main() {
 	flag.StringVar(&namespace, "namespace", "default", "The namespace in which the operator runs")
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&healthProbeAddr, "health-probe-bind-address", ":8081", "The address the health probe endpoint binds to.")
+	flag.StringVar(&prometheusImage, "prometheus-image", envOrDefault(prometheusImageEnv, ""),
+		"The image of Prometheus. Defaults to the value of the "+prometheusImageEnv+" environment variable if set.")
+	flag.StringVar(&alertmanagerImage, "alertmanager-image", envOrDefault(alertmanagerImageEnv, ""),
+		"The image of Alertmanager. Defaults to the value of the "+alertmanagerImageEnv+" environment variable if set.")
+	flag.StringVar(&thanosImage, "thanos-image", envOrDefault(thanosImageEnv, operator.DefaultThanosImage),
+		"The image of the Thanos sidecar and Thanos Querier. Defaults to the value of the "+thanosImageEnv+" environment variable if set.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", operator.DefaultWebhookCertDir,
+		"The directory holding the serving certificate of the webhook server. A certificate is provisioned when the directory doesn't contain one.")
 	opts := zap.Options{
 		Development: true,
 		TimeEncoder: zapcore.RFC3339TimeEncoder,
@@ -50,9 +77,21 @@ func main() {
 
 	setupLog.Info("running with arguments",
 		"namespace", namespace,
-		"metrics-bind-address", metricsAddr)
+		"metrics-bind-address", metricsAddr,
+		"prometheus-image", prometheusImage,
+		"alertmanager-image", alertmanagerImage,
+		"thanos-image", thanosImage,
+		"webhook-cert-dir", webhookCertDir)
 
-	op, err := operator.New(metricsAddr, healthProbeAddr)
+	op, err := operator.New(&operator.OperatorConfiguration{
+		Namespace:         namespace,
+		MetricsAddr:       metricsAddr,
+		HealthProbeAddr:   healthProbeAddr,
+		PrometheusImage:   prometheusImage,
+		AlertmanagerImage: alertmanagerImage,
+		ThanosImage:       thanosImage,
+		WebhookCertDir:    webhookCertDir,
+	})
 	if err != nil {
 		setupLog.Error(err, "cannot create a new operator")
 		os.Exit(1)
@@ -0,0 +1,10 @@
+package v1alpha1
+
+// Hub marks MonitoringStack as the version other versions are converted
+// to and from. It is the version stored by the API server and used by the
+// operator.
+func (*MonitoringStack) Hub() {}
+
+// Hub marks ThanosQuerier as the version other versions are converted to and
+// from. It is the version stored by the API server and used by the operator.
+func (*ThanosQuerier) Hub() {}
@@ -31,6 +31,22 @@ import (
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AlertmanagerConfig) DeepCopyInto(out *AlertmanagerConfig) {
 	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(corev1.PersistentVolumeClaimSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Scheduling != nil {
+		in, out := &in.Scheduling, &out.Scheduling
+		*out = new(SchedulingConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertmanagerConfig.
@@ -43,6 +59,32 @@ func (in *AlertmanagerConfig) DeepCopy() *AlertmanagerConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CompactorConfig) DeepCopyInto(out *CompactorConfig) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(corev1.PersistentVolumeClaimSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(RetentionByResolution)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CompactorConfig.
+func (in *CompactorConfig) DeepCopy() *CompactorConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CompactorConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Condition) DeepCopyInto(out *Condition) {
 	*out = *in
@@ -59,6 +101,117 @@ func (in *Condition) DeepCopy() *Condition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigReloaderConfig) DeepCopyInto(out *ConfigReloaderConfig) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigReloaderConfig.
+func (in *ConfigReloaderConfig) DeepCopy() *ConfigReloaderConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigReloaderConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiscoveredResources) DeepCopyInto(out *DiscoveredResources) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiscoveredResources.
+func (in *DiscoveredResources) DeepCopy() *DiscoveredResources {
+	if in == nil {
+		return nil
+	}
+	out := new(DiscoveredResources)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExposeConfig) DeepCopyInto(out *ExposeConfig) {
+	*out = *in
+	if in.Prometheus != nil {
+		in, out := &in.Prometheus, &out.Prometheus
+		*out = new(ExposedEndpoint)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Alertmanager != nil {
+		in, out := &in.Alertmanager, &out.Alertmanager
+		*out = new(ExposedEndpoint)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExposeConfig.
+func (in *ExposeConfig) DeepCopy() *ExposeConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ExposeConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExposedEndpoint) DeepCopyInto(out *ExposedEndpoint) {
+	*out = *in
+	if in.IngressClassName != nil {
+		in, out := &in.IngressClassName, &out.IngressClassName
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExposedEndpoint.
+func (in *ExposedEndpoint) DeepCopy() *ExposedEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(ExposedEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InMemoryCacheConfig) DeepCopyInto(out *InMemoryCacheConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InMemoryCacheConfig.
+func (in *InMemoryCacheConfig) DeepCopy() *InMemoryCacheConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(InMemoryCacheConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemcachedCacheConfig) DeepCopyInto(out *MemcachedCacheConfig) {
+	*out = *in
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemcachedCacheConfig.
+func (in *MemcachedCacheConfig) DeepCopy() *MemcachedCacheConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(MemcachedCacheConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MonitoringStack) DeepCopyInto(out *MonitoringStack) {
 	*out = *in
@@ -137,7 +290,32 @@ func (in *MonitoringStackSpec) DeepCopyInto(out *MonitoringStackSpec) {
 		*out = new(PrometheusConfig)
 		(*in).DeepCopyInto(*out)
 	}
-	out.AlertmanagerConfig = in.AlertmanagerConfig
+	in.AlertmanagerConfig.DeepCopyInto(&out.AlertmanagerConfig)
+	if in.ThanosSidecarConfig != nil {
+		in, out := &in.ThanosSidecarConfig, &out.ThanosSidecarConfig
+		*out = new(ThanosSidecarConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConfigReloaderConfig != nil {
+		in, out := &in.ConfigReloaderConfig, &out.ConfigReloaderConfig
+		*out = new(ConfigReloaderConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Web != nil {
+		in, out := &in.Web, &out.Web
+		*out = new(WebConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(TLSConfig)
+		**out = **in
+	}
+	if in.ObjectStorage != nil {
+		in, out := &in.ObjectStorage, &out.ObjectStorage
+		*out = new(ObjectStorageConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackSpec.
@@ -160,6 +338,16 @@ func (in *MonitoringStackStatus) DeepCopyInto(out *MonitoringStackStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ObjectStorage != nil {
+		in, out := &in.ObjectStorage, &out.ObjectStorage
+		*out = new(ObjectStorageStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DiscoveredResources != nil {
+		in, out := &in.DiscoveredResources, &out.DiscoveredResources
+		*out = new(DiscoveredResources)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackStatus.
@@ -180,6 +368,11 @@ func (in *NamespaceSelector) DeepCopyInto(out *NamespaceSelector) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceSelector.
@@ -192,6 +385,41 @@ func (in *NamespaceSelector) DeepCopy() *NamespaceSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStorageConfig) DeepCopyInto(out *ObjectStorageConfig) {
+	*out = *in
+	in.Secret.DeepCopyInto(&out.Secret)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStorageConfig.
+func (in *ObjectStorageConfig) DeepCopy() *ObjectStorageConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStorageConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStorageStatus) DeepCopyInto(out *ObjectStorageStatus) {
+	*out = *in
+	if in.LastSuccessfulUploadTime != nil {
+		in, out := &in.LastSuccessfulUploadTime, &out.LastSuccessfulUploadTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStorageStatus.
+func (in *ObjectStorageStatus) DeepCopy() *ObjectStorageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStorageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PrometheusConfig) DeepCopyInto(out *PrometheusConfig) {
 	*out = *in
@@ -224,6 +452,11 @@ func (in *PrometheusConfig) DeepCopyInto(out *PrometheusConfig) {
 		*out = new(monitoringv1.Duration)
 		**out = **in
 	}
+	if in.Scheduling != nil {
+		in, out := &in.Scheduling, &out.Scheduling
+		*out = new(SchedulingConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusConfig.
@@ -236,13 +469,231 @@ func (in *PrometheusConfig) DeepCopy() *PrometheusConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QueryFrontendCacheConfig) DeepCopyInto(out *QueryFrontendCacheConfig) {
+	*out = *in
+	if in.InMemory != nil {
+		in, out := &in.InMemory, &out.InMemory
+		*out = new(InMemoryCacheConfig)
+		**out = **in
+	}
+	if in.Memcached != nil {
+		in, out := &in.Memcached, &out.Memcached
+		*out = new(MemcachedCacheConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Redis != nil {
+		in, out := &in.Redis, &out.Redis
+		*out = new(RedisCacheConfig)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QueryFrontendCacheConfig.
+func (in *QueryFrontendCacheConfig) DeepCopy() *QueryFrontendCacheConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(QueryFrontendCacheConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisCacheConfig) DeepCopyInto(out *RedisCacheConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisCacheConfig.
+func (in *RedisCacheConfig) DeepCopy() *RedisCacheConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisCacheConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetentionByResolution) DeepCopyInto(out *RetentionByResolution) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetentionByResolution.
+func (in *RetentionByResolution) DeepCopy() *RetentionByResolution {
+	if in == nil {
+		return nil
+	}
+	out := new(RetentionByResolution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchedulingConfig) DeepCopyInto(out *SchedulingConfig) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]corev1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchedulingConfig.
+func (in *SchedulingConfig) DeepCopy() *SchedulingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(SchedulingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SelectedMonitoringStack) DeepCopyInto(out *SelectedMonitoringStack) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SelectedMonitoringStack.
+func (in *SelectedMonitoringStack) DeepCopy() *SelectedMonitoringStack {
+	if in == nil {
+		return nil
+	}
+	out := new(SelectedMonitoringStack)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreGatewayConfig) DeepCopyInto(out *StoreGatewayConfig) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(corev1.PersistentVolumeClaimSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreGatewayConfig.
+func (in *StoreGatewayConfig) DeepCopy() *StoreGatewayConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreGatewayConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSConfig) DeepCopyInto(out *TLSConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSConfig.
+func (in *TLSConfig) DeepCopy() *TLSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosEndpoint) DeepCopyInto(out *ThanosEndpoint) {
+	*out = *in
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(ThanosEndpointService)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosEndpoint.
+func (in *ThanosEndpoint) DeepCopy() *ThanosEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosEndpointService) DeepCopyInto(out *ThanosEndpointService) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosEndpointService.
+func (in *ThanosEndpointService) DeepCopy() *ThanosEndpointService {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosEndpointService)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosObjectStorageConfig) DeepCopyInto(out *ThanosObjectStorageConfig) {
+	*out = *in
+	in.Secret.DeepCopyInto(&out.Secret)
+	if in.StoreGateway != nil {
+		in, out := &in.StoreGateway, &out.StoreGateway
+		*out = new(StoreGatewayConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Compactor != nil {
+		in, out := &in.Compactor, &out.Compactor
+		*out = new(CompactorConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosObjectStorageConfig.
+func (in *ThanosObjectStorageConfig) DeepCopy() *ThanosObjectStorageConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosObjectStorageConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ThanosQuerier) DeepCopyInto(out *ThanosQuerier) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerier.
@@ -305,6 +756,54 @@ func (in *ThanosQuerierSpec) DeepCopyInto(out *ThanosQuerierSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(ThanosQuerierTLSConfig)
+		**out = **in
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Scheduling != nil {
+		in, out := &in.Scheduling, &out.Scheduling
+		*out = new(SchedulingConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Query != nil {
+		in, out := &in.Query, &out.Query
+		*out = new(ThanosQueryConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HTTPPort != nil {
+		in, out := &in.HTTPPort, &out.HTTPPort
+		*out = new(int32)
+		**out = **in
+	}
+	if in.GRPCPort != nil {
+		in, out := &in.GRPCPort, &out.GRPCPort
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AdditionalEndpoints != nil {
+		in, out := &in.AdditionalEndpoints, &out.AdditionalEndpoints
+		*out = make([]ThanosEndpoint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.QueryFrontend != nil {
+		in, out := &in.QueryFrontend, &out.QueryFrontend
+		*out = new(ThanosQueryFrontendConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ObjectStorage != nil {
+		in, out := &in.ObjectStorage, &out.ObjectStorage
+		*out = new(ThanosObjectStorageConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerierSpec.
@@ -320,6 +819,23 @@ func (in *ThanosQuerierSpec) DeepCopy() *ThanosQuerierSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ThanosQuerierStatus) DeepCopyInto(out *ThanosQuerierStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SelectedStacks != nil {
+		in, out := &in.SelectedStacks, &out.SelectedStacks
+		*out = make([]SelectedMonitoringStack, len(*in))
+		copy(*out, *in)
+	}
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerierStatus.
@@ -331,3 +847,125 @@ func (in *ThanosQuerierStatus) DeepCopy() *ThanosQuerierStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosQuerierTLSConfig) DeepCopyInto(out *ThanosQuerierTLSConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerierTLSConfig.
+func (in *ThanosQuerierTLSConfig) DeepCopy() *ThanosQuerierTLSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosQuerierTLSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosQueryConfig) DeepCopyInto(out *ThanosQueryConfig) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+	if in.MaxConcurrent != nil {
+		in, out := &in.MaxConcurrent, &out.MaxConcurrent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.LookbackDelta != nil {
+		in, out := &in.LookbackDelta, &out.LookbackDelta
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+	if in.DefaultEvaluationInterval != nil {
+		in, out := &in.DefaultEvaluationInterval, &out.DefaultEvaluationInterval
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQueryConfig.
+func (in *ThanosQueryConfig) DeepCopy() *ThanosQueryConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosQueryConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosQueryFrontendConfig) DeepCopyInto(out *ThanosQueryFrontendConfig) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.SplitInterval != nil {
+		in, out := &in.SplitInterval, &out.SplitInterval
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+	if in.MaxRetries != nil {
+		in, out := &in.MaxRetries, &out.MaxRetries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Cache != nil {
+		in, out := &in.Cache, &out.Cache
+		*out = new(QueryFrontendCacheConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQueryFrontendConfig.
+func (in *ThanosQueryFrontendConfig) DeepCopy() *ThanosQueryFrontendConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosQueryFrontendConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosSidecarConfig) DeepCopyInto(out *ThanosSidecarConfig) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosSidecarConfig.
+func (in *ThanosSidecarConfig) DeepCopy() *ThanosSidecarConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosSidecarConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebConfig) DeepCopyInto(out *WebConfig) {
+	*out = *in
+	if in.Expose != nil {
+		in, out := &in.Expose, &out.Expose
+		*out = new(ExposeConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebConfig.
+func (in *WebConfig) DeepCopy() *WebConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(WebConfig)
+	in.DeepCopyInto(out)
+	return out
+}
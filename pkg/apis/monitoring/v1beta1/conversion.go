@@ -0,0 +1,479 @@
+package v1beta1
+
+import (
+	"github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// ConvertTo converts the MonitoringStack to the v1alpha1 hub version.
+func (src *MonitoringStack) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.MonitoringStack)
+	dst.ObjectMeta = src.ObjectMeta
+
+	spec := src.Spec
+	dst.Spec = v1alpha1.MonitoringStackSpec{
+		LogLevel:          v1alpha1.LogLevel(spec.LogLevel),
+		ResourceSelector:  spec.ResourceSelector,
+		NamespaceSelector: spec.NamespaceSelector,
+		Retention:         spec.Prometheus.Retention,
+		Resources:         spec.Prometheus.Resources,
+		PrometheusConfig: &v1alpha1.PrometheusConfig{
+			Replicas:                  spec.Prometheus.Replicas,
+			RemoteWrite:               spec.Prometheus.RemoteWrite,
+			PersistentVolumeClaim:     spec.Prometheus.PersistentVolumeClaim,
+			ExternalLabels:            spec.Prometheus.ExternalLabels,
+			EnableRemoteWriteReceiver: spec.Prometheus.EnableRemoteWriteReceiver,
+			ScrapeInterval:            spec.Prometheus.ScrapeInterval,
+			Scheduling:                convertSchedulingTo(spec.Prometheus.Scheduling),
+		},
+		AlertmanagerConfig: v1alpha1.AlertmanagerConfig{
+			Disabled:              spec.Alertmanager.Disabled,
+			Replicas:              spec.Alertmanager.Replicas,
+			Resources:             spec.Alertmanager.Resources,
+			Retention:             spec.Alertmanager.Retention,
+			PersistentVolumeClaim: spec.Alertmanager.PersistentVolumeClaim,
+			LogLevel:              v1alpha1.LogLevel(spec.Alertmanager.LogLevel),
+			Scheduling:            convertSchedulingTo(spec.Alertmanager.Scheduling),
+		},
+	}
+	if spec.ThanosSidecar != nil {
+		dst.Spec.ThanosSidecarConfig = &v1alpha1.ThanosSidecarConfig{
+			Image:     spec.ThanosSidecar.Image,
+			Resources: spec.ThanosSidecar.Resources,
+		}
+	}
+	if spec.ConfigReloader != nil {
+		dst.Spec.ConfigReloaderConfig = &v1alpha1.ConfigReloaderConfig{
+			Resources: spec.ConfigReloader.Resources,
+		}
+	}
+	if spec.Web != nil {
+		dst.Spec.Web = &v1alpha1.WebConfig{}
+		if spec.Web.Expose != nil {
+			dst.Spec.Web.Expose = &v1alpha1.ExposeConfig{
+				Prometheus:   (*v1alpha1.ExposedEndpoint)(spec.Web.Expose.Prometheus),
+				Alertmanager: (*v1alpha1.ExposedEndpoint)(spec.Web.Expose.Alertmanager),
+			}
+		}
+	}
+	dst.Spec.TLS = (*v1alpha1.TLSConfig)(spec.TLS)
+	dst.Spec.ObjectStorage = (*v1alpha1.ObjectStorageConfig)(spec.ObjectStorage)
+
+	dst.Status = v1alpha1.MonitoringStackStatus{
+		Conditions:          convertConditionsTo(src.Status.Conditions),
+		ObjectStorage:       (*v1alpha1.ObjectStorageStatus)(src.Status.ObjectStorage),
+		DiscoveredResources: (*v1alpha1.DiscoveredResources)(src.Status.DiscoveredResources),
+	}
+	return nil
+}
+
+// ConvertFrom converts the v1alpha1 hub version to this version.
+func (dst *MonitoringStack) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.MonitoringStack)
+	dst.ObjectMeta = src.ObjectMeta
+
+	spec := src.Spec
+	dst.Spec = MonitoringStackSpec{
+		LogLevel:          LogLevel(spec.LogLevel),
+		ResourceSelector:  spec.ResourceSelector,
+		NamespaceSelector: spec.NamespaceSelector,
+		Prometheus: PrometheusSpec{
+			Retention: spec.Retention,
+			Resources: spec.Resources,
+		},
+		Alertmanager: AlertmanagerSpec{
+			Disabled:              spec.AlertmanagerConfig.Disabled,
+			Replicas:              spec.AlertmanagerConfig.Replicas,
+			Resources:             spec.AlertmanagerConfig.Resources,
+			Retention:             spec.AlertmanagerConfig.Retention,
+			PersistentVolumeClaim: spec.AlertmanagerConfig.PersistentVolumeClaim,
+			LogLevel:              LogLevel(spec.AlertmanagerConfig.LogLevel),
+			Scheduling:            convertSchedulingFrom(spec.AlertmanagerConfig.Scheduling),
+		},
+	}
+	// prometheusConfig is always set by the API server since it has a default
+	if pc := spec.PrometheusConfig; pc != nil {
+		dst.Spec.Prometheus.Replicas = pc.Replicas
+		dst.Spec.Prometheus.RemoteWrite = pc.RemoteWrite
+		dst.Spec.Prometheus.PersistentVolumeClaim = pc.PersistentVolumeClaim
+		dst.Spec.Prometheus.ExternalLabels = pc.ExternalLabels
+		dst.Spec.Prometheus.EnableRemoteWriteReceiver = pc.EnableRemoteWriteReceiver
+		dst.Spec.Prometheus.ScrapeInterval = pc.ScrapeInterval
+		dst.Spec.Prometheus.Scheduling = convertSchedulingFrom(pc.Scheduling)
+	}
+	if spec.ThanosSidecarConfig != nil {
+		dst.Spec.ThanosSidecar = &ThanosSidecarSpec{
+			Image:     spec.ThanosSidecarConfig.Image,
+			Resources: spec.ThanosSidecarConfig.Resources,
+		}
+	}
+	if spec.ConfigReloaderConfig != nil {
+		dst.Spec.ConfigReloader = &ConfigReloaderSpec{
+			Resources: spec.ConfigReloaderConfig.Resources,
+		}
+	}
+	if spec.Web != nil {
+		dst.Spec.Web = &WebConfig{}
+		if spec.Web.Expose != nil {
+			dst.Spec.Web.Expose = &ExposeConfig{
+				Prometheus:   (*ExposedEndpoint)(spec.Web.Expose.Prometheus),
+				Alertmanager: (*ExposedEndpoint)(spec.Web.Expose.Alertmanager),
+			}
+		}
+	}
+	dst.Spec.TLS = (*TLSConfig)(spec.TLS)
+	dst.Spec.ObjectStorage = (*ObjectStorageConfig)(spec.ObjectStorage)
+
+	dst.Status = MonitoringStackStatus{
+		Conditions:          convertConditionsFrom(src.Status.Conditions),
+		ObjectStorage:       (*ObjectStorageStatus)(src.Status.ObjectStorage),
+		DiscoveredResources: (*DiscoveredResources)(src.Status.DiscoveredResources),
+	}
+	return nil
+}
+
+// ConvertTo converts the ThanosQuerier to the v1alpha1 hub version.
+func (src *ThanosQuerier) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.ThanosQuerier)
+	dst.ObjectMeta = src.ObjectMeta
+
+	namespaceSelector := convertNamespaceSelectorTo(src.Spec.NamespaceSelector)
+	if _, ok := src.Annotations[namespaceLabelSelectorAnnotation]; ok {
+		dst.Annotations = withoutAnnotation(src.Annotations, namespaceLabelSelectorAnnotation)
+		if src.Spec.NamespaceSelector != nil {
+			namespaceSelector = v1alpha1.NamespaceSelector{LabelSelector: src.Spec.NamespaceSelector}
+		}
+	}
+
+	dst.Spec = v1alpha1.ThanosQuerierSpec{
+		Selector:          src.Spec.Selector,
+		NamespaceSelector: namespaceSelector,
+		ReplicaLabels:     src.Spec.ReplicaLabels,
+		Image:             src.Spec.Image,
+		TLS:               (*v1alpha1.ThanosQuerierTLSConfig)(src.Spec.TLS),
+		Replicas:          src.Spec.Replicas,
+		Resources:         src.Spec.Resources,
+		Scheduling:        convertSchedulingTo(src.Spec.Scheduling),
+		LogLevel:          v1alpha1.LogLevel(src.Spec.LogLevel),
+		Query:             convertQueryConfigTo(src.Spec.Query),
+		HTTPPort:          src.Spec.HTTPPort,
+		GRPCPort:          src.Spec.GRPCPort,
+		QueryFrontend:     convertQueryFrontendTo(src.Spec.QueryFrontend),
+		ObjectStorage:     convertThanosObjectStorageTo(src.Spec.ObjectStorage),
+	}
+	if src.Spec.AdditionalEndpoints != nil {
+		dst.Spec.AdditionalEndpoints = make([]v1alpha1.ThanosEndpoint, len(src.Spec.AdditionalEndpoints))
+		for i, e := range src.Spec.AdditionalEndpoints {
+			dst.Spec.AdditionalEndpoints[i] = v1alpha1.ThanosEndpoint{
+				Address:    e.Address,
+				DNSSRVName: e.DNSSRVName,
+				Service:    (*v1alpha1.ThanosEndpointService)(e.Service),
+			}
+		}
+	}
+
+	dst.Status = v1alpha1.ThanosQuerierStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         convertConditionsTo(src.Status.Conditions),
+		Endpoints:          src.Status.Endpoints,
+	}
+	if src.Status.SelectedStacks != nil {
+		dst.Status.SelectedStacks = make([]v1alpha1.SelectedMonitoringStack, len(src.Status.SelectedStacks))
+		for i, s := range src.Status.SelectedStacks {
+			dst.Status.SelectedStacks[i] = v1alpha1.SelectedMonitoringStack(s)
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts the v1alpha1 hub version to this version.
+func (dst *ThanosQuerier) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.ThanosQuerier)
+	dst.ObjectMeta = src.ObjectMeta
+
+	// a label selector selecting all namespaces or namespaces by name would
+	// come back as any or matchNames, hence its shape is kept in an annotation
+	if ls := src.Spec.NamespaceSelector.LabelSelector; ls != nil && convertNamespaceSelectorTo(ls).LabelSelector == nil {
+		dst.Annotations = withAnnotation(src.Annotations, namespaceLabelSelectorAnnotation, "true")
+	}
+
+	dst.Spec = ThanosQuerierSpec{
+		Selector:          src.Spec.Selector,
+		NamespaceSelector: convertNamespaceSelectorFrom(src.Spec.NamespaceSelector),
+		ReplicaLabels:     src.Spec.ReplicaLabels,
+		Image:             src.Spec.Image,
+		TLS:               (*ThanosQuerierTLSConfig)(src.Spec.TLS),
+		Replicas:          src.Spec.Replicas,
+		Resources:         src.Spec.Resources,
+		Scheduling:        convertSchedulingFrom(src.Spec.Scheduling),
+		LogLevel:          LogLevel(src.Spec.LogLevel),
+		Query:             convertQueryConfigFrom(src.Spec.Query),
+		HTTPPort:          src.Spec.HTTPPort,
+		GRPCPort:          src.Spec.GRPCPort,
+		QueryFrontend:     convertQueryFrontendFrom(src.Spec.QueryFrontend),
+		ObjectStorage:     convertThanosObjectStorageFrom(src.Spec.ObjectStorage),
+	}
+	if src.Spec.AdditionalEndpoints != nil {
+		dst.Spec.AdditionalEndpoints = make([]ThanosEndpoint, len(src.Spec.AdditionalEndpoints))
+		for i, e := range src.Spec.AdditionalEndpoints {
+			dst.Spec.AdditionalEndpoints[i] = ThanosEndpoint{
+				Address:    e.Address,
+				DNSSRVName: e.DNSSRVName,
+				Service:    (*ThanosEndpointService)(e.Service),
+			}
+		}
+	}
+
+	dst.Status = ThanosQuerierStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         convertConditionsFrom(src.Status.Conditions),
+		Endpoints:          src.Status.Endpoints,
+	}
+	if src.Status.SelectedStacks != nil {
+		dst.Status.SelectedStacks = make([]SelectedMonitoringStack, len(src.Status.SelectedStacks))
+		for i, s := range src.Status.SelectedStacks {
+			dst.Status.SelectedStacks[i] = SelectedMonitoringStack(s)
+		}
+	}
+	return nil
+}
+
+// namespaceLabelSelectorAnnotation marks the ThanosQueriers of which the
+// v1alpha1 namespace selector is a label selector which would otherwise be
+// converted back to any or matchNames.
+const namespaceLabelSelectorAnnotation = "monitoring.rhobs/v1alpha1-namespace-label-selector"
+
+// withAnnotation returns a copy of the annotations with the given annotation,
+// since the annotations are shared with the converted object.
+func withAnnotation(annotations map[string]string, key, value string) map[string]string {
+	res := make(map[string]string, len(annotations)+1)
+	for k, v := range annotations {
+		res[k] = v
+	}
+	res[key] = value
+	return res
+}
+
+// withoutAnnotation returns a copy of the annotations without the given
+// annotation, or nil when no annotation is left.
+func withoutAnnotation(annotations map[string]string, key string) map[string]string {
+	if len(annotations) <= 1 {
+		return nil
+	}
+	res := make(map[string]string, len(annotations)-1)
+	for k, v := range annotations {
+		if k != key {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// convertNamespaceSelectorTo maps the empty selector to all namespaces and a
+// single In requirement on the name label of namespaces to a list of names.
+// Other selectors are label selectors in both versions.
+func convertNamespaceSelectorTo(selector *metav1.LabelSelector) v1alpha1.NamespaceSelector {
+	switch {
+	case selector == nil:
+		return v1alpha1.NamespaceSelector{}
+	case len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0:
+		return v1alpha1.NamespaceSelector{Any: true}
+	case len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 1:
+		req := selector.MatchExpressions[0]
+		if req.Key == corev1.LabelMetadataName && req.Operator == metav1.LabelSelectorOpIn && len(req.Values) > 0 {
+			return v1alpha1.NamespaceSelector{MatchNames: req.Values}
+		}
+	}
+	return v1alpha1.NamespaceSelector{LabelSelector: selector}
+}
+
+func convertNamespaceSelectorFrom(selector v1alpha1.NamespaceSelector) *metav1.LabelSelector {
+	switch {
+	case selector.Any:
+		return &metav1.LabelSelector{}
+	case selector.LabelSelector != nil:
+		return selector.LabelSelector
+	case len(selector.MatchNames) > 0:
+		return &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      corev1.LabelMetadataName,
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   selector.MatchNames,
+			}},
+		}
+	}
+	return nil
+}
+
+func convertSchedulingTo(in *SchedulingConfig) *v1alpha1.SchedulingConfig {
+	if in == nil {
+		return nil
+	}
+	return &v1alpha1.SchedulingConfig{
+		NodeSelector:              in.NodeSelector,
+		Tolerations:               in.Tolerations,
+		TopologySpreadConstraints: in.TopologySpreadConstraints,
+		PriorityClassName:         in.PriorityClassName,
+		Affinity:                  in.Affinity,
+		PodAntiAffinity:           v1alpha1.PodAntiAffinityMode(in.PodAntiAffinity),
+	}
+}
+
+func convertSchedulingFrom(in *v1alpha1.SchedulingConfig) *SchedulingConfig {
+	if in == nil {
+		return nil
+	}
+	return &SchedulingConfig{
+		NodeSelector:              in.NodeSelector,
+		Tolerations:               in.Tolerations,
+		TopologySpreadConstraints: in.TopologySpreadConstraints,
+		PriorityClassName:         in.PriorityClassName,
+		Affinity:                  in.Affinity,
+		PodAntiAffinity:           PodAntiAffinityMode(in.PodAntiAffinity),
+	}
+}
+
+func convertQueryConfigTo(in *ThanosQueryConfig) *v1alpha1.ThanosQueryConfig {
+	if in == nil {
+		return nil
+	}
+	return &v1alpha1.ThanosQueryConfig{
+		Timeout:                   in.Timeout,
+		MaxConcurrent:             in.MaxConcurrent,
+		LookbackDelta:             in.LookbackDelta,
+		DefaultEvaluationInterval: in.DefaultEvaluationInterval,
+		PartialResponseStrategy:   v1alpha1.PartialResponseStrategy(in.PartialResponseStrategy),
+		DisableAutoDownsampling:   in.DisableAutoDownsampling,
+		DisableDeduplication:      in.DisableDeduplication,
+	}
+}
+
+func convertQueryConfigFrom(in *v1alpha1.ThanosQueryConfig) *ThanosQueryConfig {
+	if in == nil {
+		return nil
+	}
+	return &ThanosQueryConfig{
+		Timeout:                   in.Timeout,
+		MaxConcurrent:             in.MaxConcurrent,
+		LookbackDelta:             in.LookbackDelta,
+		DefaultEvaluationInterval: in.DefaultEvaluationInterval,
+		PartialResponseStrategy:   PartialResponseStrategy(in.PartialResponseStrategy),
+		DisableAutoDownsampling:   in.DisableAutoDownsampling,
+		DisableDeduplication:      in.DisableDeduplication,
+	}
+}
+
+func convertQueryFrontendTo(in *ThanosQueryFrontendConfig) *v1alpha1.ThanosQueryFrontendConfig {
+	if in == nil {
+		return nil
+	}
+	out := &v1alpha1.ThanosQueryFrontendConfig{
+		Replicas:      in.Replicas,
+		Resources:     in.Resources,
+		SplitInterval: in.SplitInterval,
+		MaxRetries:    in.MaxRetries,
+	}
+	if in.Cache != nil {
+		out.Cache = &v1alpha1.QueryFrontendCacheConfig{
+			InMemory:  (*v1alpha1.InMemoryCacheConfig)(in.Cache.InMemory),
+			Memcached: (*v1alpha1.MemcachedCacheConfig)(in.Cache.Memcached),
+			Redis:     (*v1alpha1.RedisCacheConfig)(in.Cache.Redis),
+		}
+	}
+	return out
+}
+
+func convertQueryFrontendFrom(in *v1alpha1.ThanosQueryFrontendConfig) *ThanosQueryFrontendConfig {
+	if in == nil {
+		return nil
+	}
+	out := &ThanosQueryFrontendConfig{
+		Replicas:      in.Replicas,
+		Resources:     in.Resources,
+		SplitInterval: in.SplitInterval,
+		MaxRetries:    in.MaxRetries,
+	}
+	if in.Cache != nil {
+		out.Cache = &QueryFrontendCacheConfig{
+			InMemory:  (*InMemoryCacheConfig)(in.Cache.InMemory),
+			Memcached: (*MemcachedCacheConfig)(in.Cache.Memcached),
+			Redis:     (*RedisCacheConfig)(in.Cache.Redis),
+		}
+	}
+	return out
+}
+
+func convertThanosObjectStorageTo(in *ThanosObjectStorageConfig) *v1alpha1.ThanosObjectStorageConfig {
+	if in == nil {
+		return nil
+	}
+	out := &v1alpha1.ThanosObjectStorageConfig{
+		Secret:       in.Secret,
+		StoreGateway: (*v1alpha1.StoreGatewayConfig)(in.StoreGateway),
+	}
+	if in.Compactor != nil {
+		out.Compactor = &v1alpha1.CompactorConfig{
+			Resources:             in.Compactor.Resources,
+			PersistentVolumeClaim: in.Compactor.PersistentVolumeClaim,
+			Retention:             (*v1alpha1.RetentionByResolution)(in.Compactor.Retention),
+		}
+	}
+	return out
+}
+
+func convertThanosObjectStorageFrom(in *v1alpha1.ThanosObjectStorageConfig) *ThanosObjectStorageConfig {
+	if in == nil {
+		return nil
+	}
+	out := &ThanosObjectStorageConfig{
+		Secret:       in.Secret,
+		StoreGateway: (*StoreGatewayConfig)(in.StoreGateway),
+	}
+	if in.Compactor != nil {
+		out.Compactor = &CompactorConfig{
+			Resources:             in.Compactor.Resources,
+			PersistentVolumeClaim: in.Compactor.PersistentVolumeClaim,
+			Retention:             (*RetentionByResolution)(in.Compactor.Retention),
+		}
+	}
+	return out
+}
+
+func convertConditionsTo(in []Condition) []v1alpha1.Condition {
+	if in == nil {
+		return nil
+	}
+	out := make([]v1alpha1.Condition, len(in))
+	for i, c := range in {
+		out[i] = v1alpha1.Condition{
+			Type:               v1alpha1.ConditionType(c.Type),
+			ObservedGeneration: c.ObservedGeneration,
+			LastTransitionTime: c.LastTransitionTime,
+			Reason:             c.Reason,
+			Message:            c.Message,
+			Status:             v1alpha1.ConditionStatus(c.Status),
+		}
+	}
+	return out
+}
+
+func convertConditionsFrom(in []v1alpha1.Condition) []Condition {
+	if in == nil {
+		return nil
+	}
+	out := make([]Condition, len(in))
+	for i, c := range in {
+		out[i] = Condition{
+			Type:               ConditionType(c.Type),
+			ObservedGeneration: c.ObservedGeneration,
+			LastTransitionTime: c.LastTransitionTime,
+			Reason:             c.Reason,
+			Message:            c.Message,
+			Status:             ConditionStatus(c.Status),
+		}
+	}
+	return out
+}
@@ -0,0 +1,209 @@
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	fuzz "github.com/google/gofuzz"
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/diff"
+)
+
+const fuzzIterations = 500
+
+// newFuzzer returns a fuzzer generating objects as they are stored by the
+// API server, i.e. without the values which are defaulted or rejected.
+func newFuzzer() *fuzz.Fuzzer {
+	return fuzz.New().NilChance(0.3).NumElements(0, 3).Funcs(
+		func(tm *metav1.TypeMeta, c fuzz.Continue) {},
+		func(q *resource.Quantity, c fuzz.Continue) {
+			*q = *resource.NewQuantity(c.Int63n(1000), resource.DecimalSI)
+		},
+		func(t *metav1.Time, c fuzz.Continue) {
+			*t = metav1.Unix(c.Int63n(1<<32), 0)
+		},
+		func(spec *v1alpha1.MonitoringStackSpec, c fuzz.Continue) {
+			c.FuzzNoCustom(spec)
+			// prometheusConfig has a default
+			if spec.PrometheusConfig == nil {
+				spec.PrometheusConfig = &v1alpha1.PrometheusConfig{}
+			}
+		},
+		func(s *v1alpha1.NamespaceSelector, c fuzz.Continue) {
+			// any, matchNames and labelSelector are mutually exclusive
+			*s = v1alpha1.NamespaceSelector{}
+			switch c.Intn(4) {
+			case 1:
+				s.Any = true
+			case 2:
+				s.MatchNames = []string{c.RandString(), c.RandString()}
+			case 3:
+				// including the label selectors selecting all namespaces or
+				// namespaces by name
+				switch c.Intn(3) {
+				case 0:
+					s.LabelSelector = &metav1.LabelSelector{}
+				case 1:
+					s.LabelSelector = namesSelector(c.RandString(), c.RandString())
+				case 2:
+					s.LabelSelector = &metav1.LabelSelector{MatchLabels: map[string]string{c.RandString(): c.RandString()}}
+					c.Fuzz(&s.LabelSelector.MatchExpressions)
+				}
+			}
+		},
+		func(spec *ThanosQuerierSpec, c fuzz.Continue) {
+			c.FuzzNoCustom(spec)
+			switch c.Intn(3) {
+			case 0:
+				spec.NamespaceSelector = &metav1.LabelSelector{}
+			case 1:
+				spec.NamespaceSelector = namesSelector(c.RandString(), c.RandString())
+			}
+		},
+	)
+}
+
+func namesSelector(names ...string) *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{
+			Key:      corev1.LabelMetadataName,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   names,
+		}},
+	}
+}
+
+func assertSemanticEqual(t *testing.T, a, b interface{}) {
+	t.Helper()
+	assert.Assert(t, apiequality.Semantic.DeepEqual(a, b), diff.ObjectReflectDiff(a, b))
+}
+
+func TestMonitoringStackRoundTrip(t *testing.T) {
+	f := newFuzzer()
+	for i := 0; i < fuzzIterations; i++ {
+		hub := &v1alpha1.MonitoringStack{}
+		f.Fuzz(hub)
+		ms := &MonitoringStack{}
+		assert.NilError(t, ms.ConvertFrom(hub.DeepCopy()))
+		restoredHub := &v1alpha1.MonitoringStack{}
+		assert.NilError(t, ms.ConvertTo(restoredHub))
+		assertSemanticEqual(t, hub, restoredHub)
+
+		ms = &MonitoringStack{}
+		f.Fuzz(ms)
+		hub = &v1alpha1.MonitoringStack{}
+		assert.NilError(t, ms.DeepCopy().ConvertTo(hub))
+		restored := &MonitoringStack{}
+		assert.NilError(t, restored.ConvertFrom(hub))
+		assertSemanticEqual(t, ms, restored)
+	}
+}
+
+func TestThanosQuerierRoundTrip(t *testing.T) {
+	f := newFuzzer()
+	for i := 0; i < fuzzIterations; i++ {
+		hub := &v1alpha1.ThanosQuerier{}
+		f.Fuzz(hub)
+		querier := &ThanosQuerier{}
+		assert.NilError(t, querier.ConvertFrom(hub.DeepCopy()))
+		restoredHub := &v1alpha1.ThanosQuerier{}
+		assert.NilError(t, querier.ConvertTo(restoredHub))
+		assertSemanticEqual(t, hub, restoredHub)
+
+		querier = &ThanosQuerier{}
+		f.Fuzz(querier)
+		hub = &v1alpha1.ThanosQuerier{}
+		assert.NilError(t, querier.DeepCopy().ConvertTo(hub))
+		restored := &ThanosQuerier{}
+		assert.NilError(t, restored.ConvertFrom(hub))
+		assertSemanticEqual(t, querier, restored)
+	}
+}
+
+func TestConvertMonitoringStack(t *testing.T) {
+	ms := &MonitoringStack{
+		Spec: MonitoringStackSpec{
+			Prometheus: PrometheusSpec{
+				Replicas:  func(i int32) *int32 { return &i }(3),
+				Retention: "30d",
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+				},
+			},
+			Alertmanager: AlertmanagerSpec{Disabled: true},
+		},
+	}
+	hub := &v1alpha1.MonitoringStack{}
+	assert.NilError(t, ms.ConvertTo(hub))
+	assert.Equal(t, *hub.Spec.PrometheusConfig.Replicas, int32(3))
+	assert.Equal(t, string(hub.Spec.Retention), "30d")
+	assert.Equal(t, hub.Spec.Resources.Limits.Memory().String(), "1Gi")
+	assert.Assert(t, hub.Spec.AlertmanagerConfig.Disabled)
+}
+
+func TestConvertNamespaceSelector(t *testing.T) {
+	tt := []struct {
+		name     string
+		selector *metav1.LabelSelector
+		expected v1alpha1.NamespaceSelector
+	}{
+		{name: "own namespace", selector: nil, expected: v1alpha1.NamespaceSelector{}},
+		{name: "all namespaces", selector: &metav1.LabelSelector{}, expected: v1alpha1.NamespaceSelector{Any: true}},
+		{
+			name:     "list of namespaces",
+			selector: namesSelector("a", "b"),
+			expected: v1alpha1.NamespaceSelector{MatchNames: []string{"a", "b"}},
+		},
+		{
+			name:     "label selector",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+			expected: v1alpha1.NamespaceSelector{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+			},
+		},
+		{
+			name: "names and labels",
+			selector: &metav1.LabelSelector{
+				MatchLabels:      map[string]string{"tenant": "a"},
+				MatchExpressions: namesSelector("a").MatchExpressions,
+			},
+			expected: v1alpha1.NamespaceSelector{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels:      map[string]string{"tenant": "a"},
+					MatchExpressions: namesSelector("a").MatchExpressions,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			querier := &ThanosQuerier{Spec: ThanosQuerierSpec{NamespaceSelector: tc.selector}}
+			hub := &v1alpha1.ThanosQuerier{}
+			assert.NilError(t, querier.ConvertTo(hub))
+			assert.DeepEqual(t, hub.Spec.NamespaceSelector, tc.expected)
+		})
+	}
+}
+
+func TestConvertNamespaceLabelSelectorRoundTrip(t *testing.T) {
+	for _, selector := range []*metav1.LabelSelector{{}, namesSelector("a", "b")} {
+		hub := &v1alpha1.ThanosQuerier{
+			Spec: v1alpha1.ThanosQuerierSpec{
+				NamespaceSelector: v1alpha1.NamespaceSelector{LabelSelector: selector},
+			},
+		}
+		querier := &ThanosQuerier{}
+		assert.NilError(t, querier.ConvertFrom(hub.DeepCopy()))
+		assert.Equal(t, querier.Annotations[namespaceLabelSelectorAnnotation], "true")
+
+		restoredHub := &v1alpha1.ThanosQuerier{}
+		assert.NilError(t, querier.ConvertTo(restoredHub))
+		assert.DeepEqual(t, restoredHub, hub)
+	}
+}
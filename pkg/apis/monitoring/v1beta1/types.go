@@ -0,0 +1,719 @@
+// +groupName=monitoring.rhobs
+
+package v1beta1
+
+import (
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MonitoringStack is the Schema for the monitoringstacks API
+// +k8s:openapi-gen=true
+// +kubebuilder:resource
+// +kubebuilder:subresource:status
+type MonitoringStack struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MonitoringStackSpec   `json:"spec,omitempty"`
+	Status MonitoringStackStatus `json:"status,omitempty"`
+}
+
+// MonitoringStackList contains a list of MonitoringStack
+// +kubebuilder:resource
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type MonitoringStackList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MonitoringStack `json:"items"`
+}
+
+// Loglevel set log levels of configured components
+// +kubebuilder:validation:Enum=debug;info;warn;error
+type LogLevel string
+
+const (
+	// Debug Log level
+	Debug LogLevel = "debug"
+
+	// Info Log level
+	Info LogLevel = "info"
+
+	// Warn Log level
+	Warn LogLevel = "warn"
+
+	// Error Log level
+	Error LogLevel = "error"
+)
+
+// MonitoringStackSpec is the specification for desired Monitoring Stack
+type MonitoringStackSpec struct {
+	// +optional
+	// +kubebuilder:default="info"
+	LogLevel LogLevel `json:"logLevel,omitempty"`
+
+	// Label selector for Monitoring Stack Resources.
+	// To monitor everything, set to empty map selector. E.g. resourceSelector: {}.
+	// To disable service discovery, set to null. E.g. resourceSelector:.
+	// +optional
+	// +nullable
+	ResourceSelector *metav1.LabelSelector `json:"resourceSelector"`
+
+	// Namespace selector for Monitoring Stack Resources.
+	// To monitor everything, set to empty map selector. E.g. namespaceSelector: {}.
+	// To monitor resources in the namespace where Monitoring Stack was created in, set to null. E.g. namespaceSelector:.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Define the Prometheus instances of the stack
+	// +optional
+	// +kubebuilder:default={replicas: 2}
+	Prometheus PrometheusSpec `json:"prometheus,omitempty"`
+
+	// Define the Alertmanager instances of the stack
+	// +optional
+	// +kubebuilder:default={disabled: false}
+	Alertmanager AlertmanagerSpec `json:"alertmanager,omitempty"`
+
+	// Define the Thanos sidecar of the Prometheus instances
+	// +optional
+	ThanosSidecar *ThanosSidecarSpec `json:"thanosSidecar,omitempty"`
+
+	// Define the config reloaders of Prometheus and Alertmanager
+	// +optional
+	ConfigReloader *ConfigReloaderSpec `json:"configReloader,omitempty"`
+
+	// Define web config of Prometheus and Alertmanager
+	// +optional
+	Web *WebConfig `json:"web,omitempty"`
+
+	// Define TLS config of the endpoints of Prometheus, Alertmanager and
+	// Thanos sidecar. The endpoints are served over plain HTTP and gRPC when
+	// not set.
+	// +optional
+	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// Define the object storage to which the Thanos sidecars upload the
+	// blocks of Prometheus for long-term storage.
+	// +optional
+	ObjectStorage *ObjectStorageConfig `json:"objectStorage,omitempty"`
+}
+
+type ObjectStorageConfig struct {
+	// Reference to the key of a secret in the namespace of the stack holding
+	// the Thanos object storage config, e.g. for S3, GCS, Azure or the
+	// filesystem.
+	Secret corev1.SecretKeySelector `json:"secret"`
+}
+
+// MonitoringStackStatus defines the observed state of MonitoringStack.
+// It should always be reconstructable from the state of the cluster and/or outside world.
+type MonitoringStackStatus struct {
+	// Conditions provide status information about the MonitoringStack
+	// +listType=atomic
+	Conditions []Condition `json:"conditions"`
+	// ObjectStorage reports the uploads of the Thanos sidecars to the object
+	// storage. It is only set when object storage is configured.
+	// +optional
+	ObjectStorage *ObjectStorageStatus `json:"objectStorage,omitempty"`
+	// DiscoveredResources reports how many resources match the resource and
+	// namespace selectors of the stack. They are counted again every 5
+	// minutes and whenever the spec of the stack changes.
+	// +optional
+	DiscoveredResources *DiscoveredResources `json:"discoveredResources,omitempty"`
+}
+
+// DiscoveredResources reports the number of resources selected by the
+// Prometheus and the Alertmanager of a MonitoringStack.
+type DiscoveredResources struct {
+	// Number of ServiceMonitors matching the selectors of the stack.
+	ServiceMonitors int32 `json:"serviceMonitors"`
+	// Number of PodMonitors matching the selectors of the stack.
+	PodMonitors int32 `json:"podMonitors"`
+	// Number of Probes matching the selectors of the stack.
+	Probes int32 `json:"probes"`
+	// Number of PrometheusRules matching the selectors of the stack.
+	PrometheusRules int32 `json:"prometheusRules"`
+	// Number of ScrapeConfigs matching the selectors of the stack.
+	ScrapeConfigs int32 `json:"scrapeConfigs"`
+	// Number of AlertmanagerConfigs matching the selectors of the stack. It
+	// is 0 when Alertmanager is disabled.
+	AlertmanagerConfigs int32 `json:"alertmanagerConfigs"`
+}
+
+// ObjectStorageStatus reports the uploads of the ready Thanos sidecars of a
+// MonitoringStack, as exposed by their metrics. The operator scrapes the
+// sidecars every 5 minutes.
+type ObjectStorageStatus struct {
+	// Number of blocks uploaded by the sidecars.
+	Uploads int64 `json:"uploads"`
+	// Number of failed uploads of the sidecars.
+	UploadFailures int64 `json:"uploadFailures"`
+	// Time of the last successful upload of the sidecars.
+	// +optional
+	LastSuccessfulUploadTime *metav1.Time `json:"lastSuccessfulUploadTime,omitempty"`
+}
+
+type ConditionStatus string
+
+// +required
+// +kubebuilder:validation:Required
+// +kubebuilder:validation:Pattern=`^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])$`
+// +kubebuilder:validation:MaxLength=316
+type ConditionType string
+
+type Condition struct {
+	// type of condition in CamelCase or in foo.example.com/CamelCase.
+	// The regex it matches is (dns1123SubdomainFmt/)?(qualifiedNameFmt)
+	Type ConditionType `json:"type"`
+	// observedGeneration represents the .metadata.generation that the condition was set based upon.
+	// For instance, if .metadata.generation is currently 12, but the .status.conditions[x].observedGeneration is 9, the condition is out of date
+	// with respect to the current state of the instance.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// lastTransitionTime is the last time the condition transitioned from one status to another.
+	// This should be when the underlying condition changed.  If that is not known, then using the time when the API field changed is acceptable.
+	// +required
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Format=date-time
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// reason contains a programmatic identifier indicating the reason for the condition's last transition.
+	// Producers of specific condition types may define expected values and meanings for this field,
+	// and whether the values are considered a guaranteed API.
+	// The value should be a CamelCase string.
+	// This field may not be empty.
+	// +required
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=1024
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=`^[A-Za-z]([A-Za-z0-9_,:]*[A-Za-z0-9_])?$`
+	Reason string `json:"reason"`
+	// message is a human readable message indicating details about the transition.
+	// This may be an empty string.
+	// +required
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=32768
+	Message string `json:"message"`
+	// status of the condition
+	// +required
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=True;False;Unknown;Degraded
+	Status ConditionStatus `json:"status"`
+}
+
+type PrometheusSpec struct {
+	// Number of replicas/pods to deploy for a Prometheus deployment.
+	// +optional
+	// +kubebuilder:default=2
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Define resources requests and limits for the Prometheus containers.
+	// +optional
+	// +kubebuilder:default={requests:{cpu: "100m", memory: "256Mi"}, limits:{memory: "512Mi", cpu: "500m"}}
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Time duration to retain data for. Default is '120h',
+	// and must match the regular expression `[0-9]+(ms|s|m|h|d|w|y)` (milliseconds seconds minutes hours days weeks years).
+	// +optional
+	// +kubebuilder:default="120h"
+	Retention monv1.Duration `json:"retention,omitempty"`
+	// Define remote write for prometheus
+	// +optional
+	RemoteWrite []monv1.RemoteWriteSpec `json:"remoteWrite,omitempty"`
+	// Define persistent volume claim for prometheus
+	// +optional
+	PersistentVolumeClaim *corev1.PersistentVolumeClaimSpec `json:"persistentVolumeClaim,omitempty"`
+	// Define ExternalLabels for prometheus
+	// +optional
+	ExternalLabels map[string]string `json:"externalLabels,omitempty"`
+	// Enable Prometheus to be used as a receiver for the Prometheus remote write protocol. Defaults to the value of `false`.
+	// +optional
+	EnableRemoteWriteReceiver bool `json:"enableRemoteWriteReceiver,omitempty"`
+	// Default interval between scrapes.
+	// +optional
+	ScrapeInterval *monv1.Duration `json:"scrapeInterval,omitempty"`
+	// Define scheduling constraints for Prometheus pods
+	// +optional
+	Scheduling *SchedulingConfig `json:"scheduling,omitempty"`
+}
+
+type AlertmanagerSpec struct {
+	// Disables the deployment of Alertmanager.
+	// +optional
+	// +kubebuilder:default=false
+	Disabled bool `json:"disabled,omitempty"`
+	// Number of replicas/pods to deploy for Alertmanager.
+	// +optional
+	// +kubebuilder:default=2
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Define resources requests and limits for the Alertmanager container.
+	// +optional
+	// +kubebuilder:default={requests:{cpu: "10m", memory: "64Mi"}, limits:{memory: "256Mi", cpu: "100m"}}
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Time duration Alertmanager retains data for. Default is '120h',
+	// and must match the regular expression `[0-9]+(ms|s|m|h)` (milliseconds seconds minutes hours).
+	// +optional
+	// +kubebuilder:default="120h"
+	Retention monv1.GoDuration `json:"retention,omitempty"`
+	// Define persistent volume claim for Alertmanager
+	// +optional
+	PersistentVolumeClaim *corev1.PersistentVolumeClaimSpec `json:"persistentVolumeClaim,omitempty"`
+	// Log level of Alertmanager. Defaults to the log level of the stack.
+	// +optional
+	LogLevel LogLevel `json:"logLevel,omitempty"`
+	// Define scheduling constraints for Alertmanager pods
+	// +optional
+	Scheduling *SchedulingConfig `json:"scheduling,omitempty"`
+}
+
+// PodAntiAffinityMode defines how strictly the replicas of a component are
+// spread across nodes.
+// +kubebuilder:validation:Enum=Required;Preferred
+type PodAntiAffinityMode string
+
+const (
+	// Replicas must run on different nodes and remain pending otherwise.
+	RequiredPodAntiAffinity PodAntiAffinityMode = "Required"
+
+	// Replicas run on different nodes when possible.
+	PreferredPodAntiAffinity PodAntiAffinityMode = "Preferred"
+)
+
+// SchedulingConfig defines where the pods of a component are scheduled.
+type SchedulingConfig struct {
+	// Define node selector for the pods.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Define tolerations for the pods.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Define topology spread constraints for the pods.
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// Define the priority class of the pods.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// Define the affinity of the pods. The pod anti-affinity terms spreading
+	// the replicas across nodes are added to the given pod anti-affinity.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// Define whether running the replicas on different nodes is required or
+	// only preferred. Use Preferred to run multiple replicas on single-node
+	// clusters.
+	// +optional
+	// +kubebuilder:default=Required
+	PodAntiAffinity PodAntiAffinityMode `json:"podAntiAffinity,omitempty"`
+}
+
+type ThanosSidecarSpec struct {
+	// Container image of the Thanos sidecar. Defaults to the Thanos image
+	// configured for the operator.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Define resources requests and limits for the Thanos sidecar container.
+	// Defaults to requests of 10m CPU and 64Mi memory and a limit of 256Mi
+	// memory when empty.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+type ConfigReloaderSpec struct {
+	// Define resources requests and limits for the config reloader containers
+	// of Prometheus and Alertmanager. The defaults of Prometheus Operator are
+	// used when empty.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+type WebConfig struct {
+	// Expose Prometheus and Alertmanager outside of the cluster.
+	// +optional
+	Expose *ExposeConfig `json:"expose,omitempty"`
+}
+
+// ExposeConfig defines which components of the stack are reachable from
+// outside of the cluster. An OpenShift Route is created for each exposed
+// component when the Route API is available and an Ingress otherwise.
+type ExposeConfig struct {
+	// Expose the web endpoint of Prometheus.
+	// +optional
+	Prometheus *ExposedEndpoint `json:"prometheus,omitempty"`
+	// Expose the web endpoint of Alertmanager. Ignored when Alertmanager is
+	// disabled.
+	// +optional
+	Alertmanager *ExposedEndpoint `json:"alertmanager,omitempty"`
+}
+
+type ExposedEndpoint struct {
+	// Host name under which the component is reachable. When empty, Ingresses
+	// match all hosts and Routes get a host generated by the router.
+	// +optional
+	Host string `json:"host,omitempty"`
+	// Path under which the component is reachable. The component serves its
+	// web endpoint under this path, also inside of the cluster.
+	// +optional
+	// +kubebuilder:default="/"
+	// +kubebuilder:validation:Pattern=`^/`
+	Path string `json:"path,omitempty"`
+	// Name of a secret of type kubernetes.io/tls in the namespace of the
+	// stack holding the certificate for the host. TLS is not terminated when
+	// empty. Routes reference the secret as an external certificate, which
+	// requires the router to be allowed to read it.
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+	// Name of the IngressClass used for the Ingress. Ignored for Routes.
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+}
+
+// TLSConfig enables TLS on the web endpoints of Prometheus and Alertmanager
+// and mutual TLS on the gRPC endpoint of the Thanos sidecar.
+type TLSConfig struct {
+	// Name of a secret in the namespace of the stack holding the certificate
+	// (tls.crt), private key (tls.key) and CA (ca.crt) of the stack
+	// components. The certificate must be valid for the services of the stack
+	// and for client authentication. The operator provisions a certificate
+	// signed by its own CA when empty.
+	// +optional
+	CertificateSecretName string `json:"certificateSecretName,omitempty"`
+}
+
+// ThanosQuerier outlines the Thanos querier components, managed by this stack
+// +k8s:openapi-gen=true
+// +kubebuilder:resource
+// +kubebuilder:subresource:status
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ThanosQuerier struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ThanosQuerierSpec   `json:"spec,omitempty"`
+	Status ThanosQuerierStatus `json:"status,omitempty"`
+}
+
+// ThanosQuerierList contains a list of ThanosQuerier
+// +kubebuilder:resource
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ThanosQuerierList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ThanosQuerier `json:"items"`
+}
+
+// ThanosQuerierSpec defines a single Thanos Querier instance. This means a
+// label selector by which Monitoring Stack instances to query are selected, and
+// an optional namespace selector and a list of replica labels by which to
+// deduplicate.
+type ThanosQuerierSpec struct {
+	// Selector to select Monitoring stacks to unify
+	Selector metav1.LabelSelector `json:"selector"`
+	// Selector to select which namespaces the Monitoring Stack objects are discovered from.
+	// To discover stacks in all namespaces, set to empty map selector. E.g. namespaceSelector: {}.
+	// To discover stacks in the namespace of the ThanosQuerier, set to null. E.g. namespaceSelector:.
+	// To discover stacks in a list of namespaces, match the names with the
+	// kubernetes.io/metadata.name label. Other selectors are resolved against
+	// the labels of the namespaces.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// Labels by which the series of the stacks are deduplicated.
+	// +optional
+	ReplicaLabels []string `json:"replicaLabels,omitempty"`
+	// Container image of Thanos Querier. Defaults to the Thanos image
+	// configured for the operator.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Define TLS config of the connections to the Thanos sidecars. All
+	// selected stacks must have TLS enabled when set.
+	// +optional
+	TLS *ThanosQuerierTLSConfig `json:"tls,omitempty"`
+	// Number of replicas of Thanos Querier. The replicas are spread across
+	// nodes and protected by a PodDisruptionBudget when greater than 1.
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Define resources requests and limits for the Thanos Querier container.
+	// Defaults to requests of 10m CPU and 64Mi memory when empty.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Define scheduling constraints of the Thanos Querier pods.
+	// +optional
+	Scheduling *SchedulingConfig `json:"scheduling,omitempty"`
+	// Log level of Thanos Querier.
+	// +optional
+	// +kubebuilder:default="info"
+	LogLevel LogLevel `json:"logLevel,omitempty"`
+	// Define the query settings of Thanos Querier.
+	// +optional
+	Query *ThanosQueryConfig `json:"query,omitempty"`
+	// Port of the HTTP API of Thanos Querier on the pods and the Service.
+	// +optional
+	// +kubebuilder:default=9090
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	HTTPPort *int32 `json:"httpPort,omitempty"`
+	// Port of the gRPC store API of Thanos Querier on the pods and the
+	// Service, which allows other queriers to query this one.
+	// +optional
+	// +kubebuilder:default=10901
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	GRPCPort *int32 `json:"grpcPort,omitempty"`
+	// Define store API endpoints queried in addition to the Thanos sidecars
+	// of the selected stacks, e.g. store gateways, receivers, rulers or
+	// sidecars outside of the cluster. The TLS config of the querier applies
+	// to these endpoints as well: the querier connects to all endpoints with
+	// the same client certificate, CA and server name, either with TLS or
+	// without. Endpoints requiring another TLS config must be queried by
+	// another ThanosQuerier.
+	// +optional
+	// +listType=atomic
+	AdditionalEndpoints []ThanosEndpoint `json:"additionalEndpoints,omitempty"`
+	// Define a Thanos Query Frontend deployed in front of the querier. It
+	// splits, retries and caches range queries.
+	// +optional
+	QueryFrontend *ThanosQueryFrontendConfig `json:"queryFrontend,omitempty"`
+	// Define the Thanos components serving and compacting the blocks
+	// uploaded to object storage.
+	// +optional
+	ObjectStorage *ThanosObjectStorageConfig `json:"objectStorage,omitempty"`
+}
+
+type ThanosObjectStorageConfig struct {
+	// Reference to the key of a secret in the namespace of the ThanosQuerier
+	// holding the Thanos object storage config.
+	Secret corev1.SecretKeySelector `json:"secret"`
+	// Define the Thanos Store Gateway serving the blocks of the object
+	// storage. It is registered as an endpoint of the querier.
+	// +optional
+	StoreGateway *StoreGatewayConfig `json:"storeGateway,omitempty"`
+	// Define the Thanos Compactor compacting and downsampling the blocks of
+	// the object storage. A single compactor may run per bucket.
+	// +optional
+	Compactor *CompactorConfig `json:"compactor,omitempty"`
+}
+
+type StoreGatewayConfig struct {
+	// Number of replicas of the store gateway.
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Define resources requests and limits for the store gateway container.
+	// Defaults to requests of 10m CPU and 64Mi memory when empty.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Define the persistent volume claim of the index cache of the store
+	// gateway. An emptyDir volume is used when not set. It can't be changed
+	// without removing the store gateway first.
+	// +optional
+	PersistentVolumeClaim *corev1.PersistentVolumeClaimSpec `json:"persistentVolumeClaim,omitempty"`
+}
+
+type CompactorConfig struct {
+	// Define resources requests and limits for the compactor container.
+	// Defaults to requests of 10m CPU and 64Mi memory when empty.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Define the persistent volume claim of the working directory of the
+	// compactor. An emptyDir volume is used when not set. It can't be changed
+	// without removing the compactor first.
+	// +optional
+	PersistentVolumeClaim *corev1.PersistentVolumeClaimSpec `json:"persistentVolumeClaim,omitempty"`
+	// Define how long the blocks of each resolution are retained.
+	// +optional
+	Retention *RetentionByResolution `json:"retention,omitempty"`
+}
+
+// RetentionByResolution defines the retention of blocks per resolution. A
+// retention of 0d retains the blocks forever, which is the default.
+type RetentionByResolution struct {
+	// Retention of raw blocks.
+	// +optional
+	Raw monv1.Duration `json:"raw,omitempty"`
+	// Retention of blocks downsampled to a 5m resolution.
+	// +optional
+	FiveMinutes monv1.Duration `json:"fiveMinutes,omitempty"`
+	// Retention of blocks downsampled to a 1h resolution.
+	// +optional
+	OneHour monv1.Duration `json:"oneHour,omitempty"`
+}
+
+type ThanosQueryFrontendConfig struct {
+	// Number of replicas of Thanos Query Frontend. The pods are scheduled
+	// according to the scheduling config of the querier.
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Define resources requests and limits for the Thanos Query Frontend
+	// container. Defaults to requests of 10m CPU and 64Mi memory when empty.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Interval by which range queries are split and executed in parallel.
+	// Defaults to 24h.
+	// +optional
+	SplitInterval *monv1.Duration `json:"splitInterval,omitempty"`
+	// Maximum number of retries of a failed query. Defaults to 5.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+	// Define the cache of query results. Results are not cached when not
+	// set.
+	// +optional
+	Cache *QueryFrontendCacheConfig `json:"cache,omitempty"`
+}
+
+// QueryFrontendCacheConfig defines the cache of query results. Exactly one of
+// inMemory, memcached and redis must be set.
+type QueryFrontendCacheConfig struct {
+	// Cache the results in the memory of the frontend.
+	// +optional
+	InMemory *InMemoryCacheConfig `json:"inMemory,omitempty"`
+	// Cache the results in memcached.
+	// +optional
+	Memcached *MemcachedCacheConfig `json:"memcached,omitempty"`
+	// Cache the results in redis.
+	// +optional
+	Redis *RedisCacheConfig `json:"redis,omitempty"`
+}
+
+type InMemoryCacheConfig struct {
+	// Maximum size of the cache, e.g. 256MB. The size is unlimited when
+	// empty.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[0-9]+(B|KB|MB|GB|TB)$`
+	MaxSize string `json:"maxSize,omitempty"`
+}
+
+type MemcachedCacheConfig struct {
+	// Addresses of the memcached servers in the host:port form.
+	// +kubebuilder:validation:MinItems=1
+	Addresses []string `json:"addresses"`
+}
+
+type RedisCacheConfig struct {
+	// Address of the redis server in the host:port form.
+	Address string `json:"address"`
+}
+
+// ThanosEndpoint defines a store API endpoint of Thanos Querier. Exactly one
+// of address, dnsSRVName and service must be set. Endpoints have no TLS config
+// of their own since the querier uses a single gRPC client config, which is
+// defined by the TLS config of the ThanosQuerier.
+type ThanosEndpoint struct {
+	// Static address of the endpoint in the host:port form.
+	// +optional
+	Address string `json:"address,omitempty"`
+	// DNS SRV name resolving to the endpoints, e.g.
+	// _grpc._tcp.thanos-store.monitoring.svc.cluster.local.
+	// +optional
+	DNSSRVName string `json:"dnsSRVName,omitempty"`
+	// Reference to a Service exposing the endpoints.
+	// +optional
+	Service *ThanosEndpointService `json:"service,omitempty"`
+}
+
+// ThanosEndpointService references a Service whose endpoints are resolved
+// through DNS SRV records.
+type ThanosEndpointService struct {
+	// Name of the Service.
+	Name string `json:"name"`
+	// Namespace of the Service. Defaults to the namespace of the
+	// ThanosQuerier.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the gRPC port of the Service.
+	// +optional
+	// +kubebuilder:default=grpc
+	Port string `json:"port,omitempty"`
+}
+
+// PartialResponseStrategy defines how Thanos Querier handles failures of a
+// subset of the store API endpoints.
+// +kubebuilder:validation:Enum=Warn;Abort
+type PartialResponseStrategy string
+
+const (
+	// Return the available data with a warning.
+	WarnPartialResponse PartialResponseStrategy = "Warn"
+
+	// Fail the query.
+	AbortPartialResponse PartialResponseStrategy = "Abort"
+)
+
+type ThanosQueryConfig struct {
+	// Maximum time to process a query. Defaults to 2m.
+	// +optional
+	Timeout *monv1.Duration `json:"timeout,omitempty"`
+	// Maximum number of queries processed concurrently. Defaults to 20.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxConcurrent *int32 `json:"maxConcurrent,omitempty"`
+	// Maximum lookback duration for retrieving series during expression
+	// evaluations. Defaults to 5m.
+	// +optional
+	LookbackDelta *monv1.Duration `json:"lookbackDelta,omitempty"`
+	// Default evaluation interval of subqueries. Defaults to 1m.
+	// +optional
+	DefaultEvaluationInterval *monv1.Duration `json:"defaultEvaluationInterval,omitempty"`
+	// Define whether queries return partial results with a warning or fail
+	// when some endpoints are unavailable. Defaults to Warn.
+	// +optional
+	PartialResponseStrategy PartialResponseStrategy `json:"partialResponseStrategy,omitempty"`
+	// Disable the automatic selection of downsampled data based on the step
+	// of queries.
+	// +optional
+	DisableAutoDownsampling bool `json:"disableAutoDownsampling,omitempty"`
+	// Disable the deduplication of series by the replica labels.
+	// +optional
+	DisableDeduplication bool `json:"disableDeduplication,omitempty"`
+}
+
+type ThanosQuerierTLSConfig struct {
+	// Name of a secret in the namespace of the ThanosQuerier holding the
+	// client certificate (tls.crt), private key (tls.key) and the CA (ca.crt)
+	// used to verify the Thanos sidecars and the additional endpoints. The
+	// operator provisions a certificate signed by its own CA when empty.
+	// +optional
+	CertificateSecretName string `json:"certificateSecretName,omitempty"`
+	// Server name used to verify the certificates of the Thanos sidecars and
+	// the additional endpoints.
+	// Defaults to the name included in the certificates provisioned by the
+	// operator.
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+}
+
+// ThanosQuerierStatus defines the observed state of ThanosQuerier.
+// It should always be reconstructable from the state of the cluster and/or outside world.
+type ThanosQuerierStatus struct {
+	// ObservedGeneration is the most recent generation of the ThanosQuerier
+	// observed by the operator.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions provide status information about the ThanosQuerier
+	// +optional
+	// +listType=atomic
+	Conditions []Condition `json:"conditions,omitempty"`
+	// SelectedStacks lists the MonitoringStacks matched by the selectors of
+	// the ThanosQuerier.
+	// +optional
+	// +listType=atomic
+	SelectedStacks []SelectedMonitoringStack `json:"selectedStacks,omitempty"`
+	// Endpoints lists the store API endpoints queried by Thanos Querier.
+	// +optional
+	// +listType=atomic
+	Endpoints []string `json:"endpoints,omitempty"`
+}
+
+// SelectedMonitoringStack identifies a MonitoringStack queried by a ThanosQuerier.
+type SelectedMonitoringStack struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
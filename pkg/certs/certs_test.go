@@ -0,0 +1,99 @@
+package certs
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestCA(t *testing.T) {
+	now := time.Now()
+	ca, err := NewCA("test", now)
+	assert.NilError(t, err)
+	assert.Assert(t, !ca.NeedsRenewal(now))
+	assert.Assert(t, ca.NeedsRenewal(now.Add(CAValidity*3/4)))
+
+	parsed, err := ParseCA(ca.CertPEM(), ca.KeyPEM())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, parsed.CertPEM(), ca.CertPEM())
+
+	other, err := NewCA("other", now)
+	assert.NilError(t, err)
+	_, err = ParseCA(ca.CertPEM(), other.KeyPEM())
+	assert.ErrorContains(t, err, "doesn't match")
+
+	certPEM, _, err := ca.NewCertificate("leaf", nil, now)
+	assert.NilError(t, err)
+	_, err = ParseCA(certPEM, ca.KeyPEM())
+	assert.ErrorContains(t, err, "not a CA")
+}
+
+func TestIsValidCertificate(t *testing.T) {
+	now := time.Now()
+	ca, err := NewCA("test", now)
+	assert.NilError(t, err)
+	other, err := NewCA("other", now)
+	assert.NilError(t, err)
+
+	dnsNames := []string{"prometheus", "prometheus.ns.svc"}
+	certPEM, keyPEM, err := ca.NewCertificate("prometheus", dnsNames, now)
+	assert.NilError(t, err)
+	_, otherKeyPEM, err := ca.NewCertificate("prometheus", dnsNames, now)
+	assert.NilError(t, err)
+
+	tt := []struct {
+		name     string
+		ca       *CA
+		keyPEM   []byte
+		dnsNames []string
+		now      time.Time
+		expected bool
+	}{
+		{
+			name:     "valid certificate",
+			ca:       ca,
+			keyPEM:   keyPEM,
+			dnsNames: []string{"prometheus.ns.svc", "prometheus"},
+			now:      now,
+			expected: true,
+		},
+		{
+			name:     "signed by another CA",
+			ca:       other,
+			keyPEM:   keyPEM,
+			dnsNames: dnsNames,
+			now:      now,
+			expected: false,
+		},
+		{
+			name:     "key doesn't match",
+			ca:       ca,
+			keyPEM:   otherKeyPEM,
+			dnsNames: dnsNames,
+			now:      now,
+			expected: false,
+		},
+		{
+			name:     "dns names changed",
+			ca:       ca,
+			keyPEM:   keyPEM,
+			dnsNames: []string{"prometheus"},
+			now:      now,
+			expected: false,
+		},
+		{
+			name:     "certificate about to expire",
+			ca:       ca,
+			keyPEM:   keyPEM,
+			dnsNames: dnsNames,
+			now:      now.Add(CertificateValidity * 3 / 4),
+			expected: false,
+		},
+	}
+
+	for _, test := range tt {
+		assert.Equal(t, test.ca.IsValidCertificate(certPEM, test.keyPEM, test.dnsNames, test.now), test.expected, test.name)
+	}
+	assert.Assert(t, !ca.IsValidCertificate([]byte("invalid"), keyPEM, dnsNames, now))
+}
@@ -0,0 +1,139 @@
+package certs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// CASecretName is the name of the secret holding the CA of the operator
+	CASecretName = "observability-operator-ca"
+	// CACommonName is the common name of the CA of the operator
+	CACommonName = "observability-operator"
+)
+
+// CAProvider loads the CA of the operator from a secret in the namespace of
+// the operator. The CA is created when the secret doesn't exist and renewed
+// before it expires.
+type CAProvider struct {
+	client    client.Client
+	namespace string
+}
+
+// NewCAProvider returns a new CAProvider for the CA stored in namespace
+func NewCAProvider(c client.Client, namespace string) *CAProvider {
+	return &CAProvider{
+		client:    c,
+		namespace: namespace,
+	}
+}
+
+// CA returns the current CA of the operator.
+func (p *CAProvider) CA(ctx context.Context) (*CA, error) {
+	now := time.Now()
+	secret := &corev1.Secret{}
+	err := p.client.Get(ctx, client.ObjectKey{Name: CASecretName, Namespace: p.namespace}, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get CA secret: %w", err)
+	}
+
+	if err == nil {
+		ca, parseErr := ParseCA(secret.Data[CertificateKey], secret.Data[PrivateKeyKey])
+		if parseErr == nil && !ca.NeedsRenewal(now) {
+			return ca, nil
+		}
+	}
+
+	ca, caErr := NewCA(CACommonName, now)
+	if caErr != nil {
+		return nil, caErr
+	}
+
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      CASecretName,
+				Namespace: p.namespace,
+			},
+			Type: corev1.SecretTypeTLS,
+		}
+		secret.Data = caData(ca)
+		if err := p.client.Create(ctx, secret); err != nil {
+			return nil, fmt.Errorf("failed to create CA secret: %w", err)
+		}
+		return ca, nil
+	}
+
+	secret.Data = caData(ca)
+	if err := p.client.Update(ctx, secret); err != nil {
+		return nil, fmt.Errorf("failed to renew CA secret: %w", err)
+	}
+	return ca, nil
+}
+
+func caData(ca *CA) map[string][]byte {
+	return map[string][]byte{
+		CertificateKey: ca.CertPEM(),
+		PrivateKeyKey:  ca.KeyPEM(),
+	}
+}
+
+// CertificateUpdater keeps a certificate signed by a CA in a secret. The
+// certificate is only replaced when it isn't valid for the CA and DNS names
+// anymore or is about to expire.
+type CertificateUpdater struct {
+	secret     *corev1.Secret
+	owner      metav1.Object
+	ca         *CA
+	commonName string
+	dnsNames   []string
+}
+
+func (r CertificateUpdater) Reconcile(ctx context.Context, c client.Client, scheme *runtime.Scheme) error {
+	existing := &corev1.Secret{}
+	err := c.Get(ctx, client.ObjectKeyFromObject(r.secret), existing)
+	if client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("%s/%s: certificate updater failed to get secret: %w",
+			r.secret.Namespace, r.secret.Name, err)
+	}
+
+	now := time.Now()
+	certPEM, keyPEM := existing.Data[CertificateKey], existing.Data[PrivateKeyKey]
+	if err != nil || !r.ca.IsValidCertificate(certPEM, keyPEM, r.dnsNames, now) {
+		certPEM, keyPEM, err = r.ca.NewCertificate(r.commonName, r.dnsNames, now)
+		if err != nil {
+			return fmt.Errorf("%s/%s: certificate updater failed to create certificate: %w",
+				r.secret.Namespace, r.secret.Name, err)
+		}
+	}
+
+	secret := r.secret.DeepCopy()
+	secret.Type = corev1.SecretTypeTLS
+	secret.Data = map[string][]byte{
+		CAKey:          r.ca.CertPEM(),
+		CertificateKey: certPEM,
+		PrivateKeyKey:  keyPEM,
+	}
+	return reconciler.NewUpdater(secret, r.owner).Reconcile(ctx, c, scheme)
+}
+
+// NewCertificateUpdater returns a CertificateUpdater for a certificate with
+// the given common name and DNS names stored in secret.
+func NewCertificateUpdater(secret *corev1.Secret, owner metav1.Object, ca *CA, commonName string, dnsNames []string) CertificateUpdater {
+	return CertificateUpdater{
+		secret:     secret,
+		owner:      owner,
+		ca:         ca,
+		commonName: commonName,
+		dnsNames:   dnsNames,
+	}
+}
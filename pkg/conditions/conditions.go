@@ -0,0 +1,85 @@
+// Package conditions maintains the conditions reported in the status of the
+// resources managed by the operator.
+package conditions
+
+import (
+	"context"
+
+	"github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Merge returns the updated conditions, keeping the previous conditions which
+// are equal to the updated ones. The last transition time of a condition is
+// only bumped when its status changes.
+func Merge(previous []v1alpha1.Condition, updated []v1alpha1.Condition) []v1alpha1.Condition {
+	merged := make([]v1alpha1.Condition, 0, len(updated))
+	for _, c := range updated {
+		for _, p := range previous {
+			if p.Type != c.Type {
+				continue
+			}
+			switch {
+			case p.Equal(c):
+				c = p
+			case p.Status == c.Status:
+				c.LastTransitionTime = p.LastTransitionTime
+			}
+			break
+		}
+		merged = append(merged, c)
+	}
+	return merged
+}
+
+// RecordTransitions records an event on obj for every condition whose status
+// differs from its previous status. Conditions becoming true are recorded as
+// Normal events and the others as Warning events.
+func RecordTransitions(recorder record.EventRecorder, obj runtime.Object, previous []v1alpha1.Condition, current []v1alpha1.Condition) {
+	for _, c := range current {
+		changed := true
+		for _, p := range previous {
+			if p.Type == c.Type {
+				changed = p.Status != c.Status
+				break
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		eventType := corev1.EventTypeWarning
+		if c.Status == v1alpha1.ConditionTrue {
+			eventType = corev1.EventTypeNormal
+		}
+		reason := c.Reason
+		if reason == "" {
+			reason = string(c.Type)
+		}
+		if c.Message == "" {
+			recorder.Eventf(obj, eventType, reason, "%s is %s", c.Type, c.Status)
+			continue
+		}
+		recorder.Eventf(obj, eventType, reason, "%s is %s: %s", c.Type, c.Status, c.Message)
+	}
+}
+
+// UpdateStatus writes the status of obj and records the transitions from the
+// previous conditions to the current ones. The status isn't written when it
+// equals the previous status, since writing an unchanged status would only
+// trigger another reconciliation.
+func UpdateStatus(ctx context.Context, c client.Client, recorder record.EventRecorder, obj client.Object, previousStatus interface{}, status interface{}, previous []v1alpha1.Condition, current []v1alpha1.Condition) error {
+	if equality.Semantic.DeepEqual(previousStatus, status) {
+		return nil
+	}
+	if err := c.Status().Update(ctx, obj); err != nil {
+		return err
+	}
+	RecordTransitions(recorder, obj, previous, current)
+	return nil
+}
@@ -0,0 +1,132 @@
+package conditions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	"gotest.tools/v3/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMerge(t *testing.T) {
+	then := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	previous := []v1alpha1.Condition{
+		{
+			Type:               v1alpha1.AvailableCondition,
+			Status:             v1alpha1.ConditionTrue,
+			Reason:             "Available",
+			Message:            "available",
+			ObservedGeneration: 1,
+			LastTransitionTime: then,
+		},
+		{
+			Type:               v1alpha1.ReconciledCondition,
+			Status:             v1alpha1.ConditionFalse,
+			Reason:             "FailedToReconcile",
+			Message:            "first error",
+			ObservedGeneration: 1,
+			LastTransitionTime: then,
+		},
+	}
+
+	// unchanged conditions are kept as they are
+	updated := []v1alpha1.Condition{previous[0], previous[1]}
+	updated[0].LastTransitionTime = metav1.Now()
+	updated[1].LastTransitionTime = metav1.Now()
+	merged := Merge(previous, updated)
+	assert.DeepEqual(t, merged, previous)
+
+	// the transition time is kept when only the message changes
+	updated[1].Message = "second error"
+	merged = Merge(previous, updated)
+	assert.Equal(t, merged[1].Message, "second error")
+	assert.Equal(t, merged[1].LastTransitionTime, then)
+
+	// the transition time is bumped when the status changes
+	now := metav1.Now()
+	updated[1] = v1alpha1.Condition{
+		Type:               v1alpha1.ReconciledCondition,
+		Status:             v1alpha1.ConditionTrue,
+		Reason:             "Reconciled",
+		ObservedGeneration: 1,
+		LastTransitionTime: now,
+	}
+	merged = Merge(previous, updated)
+	assert.Equal(t, merged[0].LastTransitionTime, then)
+	assert.Equal(t, merged[1].Status, v1alpha1.ConditionTrue)
+	assert.Equal(t, merged[1].LastTransitionTime, now)
+
+	// new conditions are added as they are
+	merged = Merge(nil, updated)
+	assert.DeepEqual(t, merged, updated)
+}
+
+func TestRecordTransitions(t *testing.T) {
+	previous := []v1alpha1.Condition{
+		{
+			Type:    v1alpha1.AvailableCondition,
+			Status:  v1alpha1.ConditionTrue,
+			Reason:  "Available",
+			Message: "available",
+		},
+		{
+			Type:   v1alpha1.ReconciledCondition,
+			Status: v1alpha1.ConditionTrue,
+			Reason: "Reconciled",
+		},
+	}
+	current := []v1alpha1.Condition{
+		{
+			Type:    v1alpha1.AvailableCondition,
+			Status:  v1alpha1.ConditionTrue,
+			Reason:  "Available",
+			Message: "updated message",
+		},
+		{
+			Type:    v1alpha1.ReconciledCondition,
+			Status:  v1alpha1.ConditionFalse,
+			Reason:  "FailedToReconcile",
+			Message: "failed",
+		},
+		{
+			Type:   v1alpha1.PrometheusAvailableCondition,
+			Status: v1alpha1.ConditionTrue,
+		},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	RecordTransitions(recorder, &v1alpha1.MonitoringStack{}, previous, current)
+	assert.Equal(t, <-recorder.Events, "Warning FailedToReconcile Reconciled is False: failed")
+	assert.Equal(t, <-recorder.Events, "Normal PrometheusAvailable PrometheusAvailable is True")
+	assert.Equal(t, len(recorder.Events), 0)
+}
+
+func TestUpdateStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NilError(t, v1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	recorder := record.NewFakeRecorder(10)
+	ctx := context.Background()
+
+	// an unchanged status isn't written
+	ms := &v1alpha1.MonitoringStack{ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "ns"}}
+	previous := ms.Status.DeepCopy()
+	assert.NilError(t, UpdateStatus(ctx, c, recorder, ms, previous, &ms.Status, previous.Conditions, ms.Status.Conditions))
+
+	// a changed status is written and its transitions are recorded
+	ms.Status.Conditions = []v1alpha1.Condition{{Type: v1alpha1.AvailableCondition, Status: v1alpha1.ConditionTrue}}
+	err := UpdateStatus(ctx, c, recorder, ms, previous, &ms.Status, previous.Conditions, ms.Status.Conditions)
+	assert.Assert(t, apierrors.IsNotFound(err))
+	assert.Equal(t, len(recorder.Events), 0)
+
+	assert.NilError(t, c.Create(ctx, ms))
+	assert.NilError(t, UpdateStatus(ctx, c, recorder, ms, previous, &ms.Status, previous.Conditions, ms.Status.Conditions))
+	assert.Equal(t, <-recorder.Events, "Normal Available Available is True")
+}
@@ -18,14 +18,37 @@ func newAlertmanager(
 	rbacResourceName string,
 	instanceSelectorKey string,
 	instanceSelectorValue string,
+	image string,
 ) *monv1.Alertmanager {
 	resourceSelector := ms.Spec.ResourceSelector
 	if resourceSelector == nil {
 		resourceSelector = &metav1.LabelSelector{}
 	}
-	replicas := int32(2)
+	config := ms.Spec.AlertmanagerConfig
+	replicas := alertmanagerReplicas(ms)
 
-	return &monv1.Alertmanager{
+	logLevel := config.LogLevel
+	if logLevel == "" {
+		logLevel = ms.Spec.LogLevel
+	}
+
+	podAntiAffinity := newPodAntiAffinity("alertmanager", ms.Name, config.Scheduling)
+	// We cannot expect all clusters to be multi-AZ, especially in the CI.
+	// This is why we set zone-spread as preferred instead of required.
+	podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+		podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+		corev1.WeightedPodAffinityTerm{
+			Weight: 100,
+			PodAffinityTerm: corev1.PodAffinityTerm{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: podLabels("alertmanager", ms.Name),
+				},
+				TopologyKey: "topology.kubernetes.io/zone",
+			},
+		},
+	)
+
+	am := &monv1.Alertmanager{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: monv1.SchemeGroupVersion.String(),
 			Kind:       "Alertmanager",
@@ -40,33 +63,15 @@ func newAlertmanager(
 				Labels: podLabels("alertmanager", ms.Name),
 			},
 			Replicas:                   &replicas,
+			Resources:                  config.Resources,
+			Retention:                  config.Retention,
+			Storage:                    storageForPVC(config.PersistentVolumeClaim),
+			LogLevel:                   string(logLevel),
+			ExternalURL:                externalURL(exposedAlertmanager(ms)),
+			RoutePrefix:                routePrefix(exposedAlertmanager(ms)),
 			ServiceAccountName:         rbacResourceName,
 			AlertmanagerConfigSelector: resourceSelector,
-			Affinity: &corev1.Affinity{
-				PodAntiAffinity: &corev1.PodAntiAffinity{
-					RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
-						{
-							TopologyKey: "kubernetes.io/hostname",
-							LabelSelector: &metav1.LabelSelector{
-								MatchLabels: podLabels("alertmanager", ms.Name),
-							},
-						},
-					},
-					// We cannot expect all clusters to be multi-AZ, especially in the CI.
-					// This is why we set zone-spread as preferred instead of required.
-					PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
-						{
-							Weight: 100,
-							PodAffinityTerm: corev1.PodAffinityTerm{
-								LabelSelector: &metav1.LabelSelector{
-									MatchLabels: podLabels("alertmanager", ms.Name),
-								},
-								TopologyKey: "topology.kubernetes.io/zone",
-							},
-						},
-					},
-				},
-			},
+			Affinity:                   newAffinity(config.Scheduling, podAntiAffinity),
 			SecurityContext: &corev1.PodSecurityContext{
 				FSGroup:      pointer.Int64(AlertmanagerUserFSGroupID),
 				RunAsNonRoot: pointer.Bool(true),
@@ -75,6 +80,41 @@ func newAlertmanager(
 			AlertmanagerConfigNamespaceSelector: ms.Spec.NamespaceSelector,
 		},
 	}
+
+	if scheduling := config.Scheduling; scheduling != nil {
+		am.Spec.NodeSelector = scheduling.NodeSelector
+		am.Spec.Tolerations = scheduling.Tolerations
+		am.Spec.TopologySpreadConstraints = scheduling.TopologySpreadConstraints
+		am.Spec.PriorityClassName = scheduling.PriorityClassName
+	}
+
+	if image != "" {
+		am.Spec.Image = stringPtr(image)
+	}
+
+	if secretName := tlsSecretName(ms); secretName != "" {
+		am.Spec.Web = &monv1.AlertmanagerWebSpec{
+			WebConfigFileFields: monv1.WebConfigFileFields{
+				TLSConfig: webTLSConfig(secretName),
+			},
+		}
+	}
+
+	if resources := configReloaderResources(ms); resources != nil {
+		am.Spec.Containers = []corev1.Container{configReloaderPatch("config-reloader", *resources)}
+		am.Spec.InitContainers = []corev1.Container{configReloaderPatch("init-config-reloader", *resources)}
+	}
+
+	return am
+}
+
+// alertmanagerReplicas returns the number of Alertmanager replicas of the
+// stack, defaulting to 2 when not set.
+func alertmanagerReplicas(ms *stack.MonitoringStack) int32 {
+	if ms.Spec.AlertmanagerConfig.Replicas == nil {
+		return 2
+	}
+	return *ms.Spec.AlertmanagerConfig.Replicas
 }
 
 func newAlertmanagerService(ms *stack.MonitoringStack, instanceSelectorKey string, instanceSelectorValue string) *corev1.Service {
@@ -135,8 +175,7 @@ func newAlertManagerClusterRole(ms *stack.MonitoringStack, rbacResourceName stri
 			Kind:       "ClusterRole",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      rbacResourceName,
-			Namespace: ms.Namespace,
+			Name: rbacResourceName,
 		},
 		Rules: []rbacv1.PolicyRule{{
 			APIGroups:     []string{"security.openshift.io"},
@@ -1,11 +1,15 @@
 package monitoringstack
 
 import (
+	"fmt"
+	"hash/fnv"
 	"reflect"
+	"strings"
 
 	"github.com/rhobs/observability-operator/pkg/reconciler"
 
 	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certs"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/pointer"
@@ -15,6 +19,7 @@ import (
 	rbacv1 "k8s.io/api/rbac/v1"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -22,43 +27,95 @@ const AdditionalScrapeConfigsSelfScrapeKey = "self-scrape-config"
 const PrometheusUserFSGroupID = 65534
 const AlertmanagerUserFSGroupID = 65535
 
-func stackComponentReconcilers(ms *stack.MonitoringStack, instanceSelectorKey string, instanceSelectorValue string) []reconciler.Reconciler {
+// maxClusterScopedNameLength limits the names generated for cluster-scoped
+// resources so that they remain valid DNS labels.
+const maxClusterScopedNameLength = 63
+
+// componentImages holds the container images of the workloads of a stack. An
+// empty image leaves the choice of the image to Prometheus Operator.
+type componentImages struct {
+	prometheus   string
+	alertmanager string
+	thanos       string
+}
+
+func stackComponentReconcilers(ms *stack.MonitoringStack, instanceSelectorKey string, instanceSelectorValue string, images componentImages, routesSupported bool, ca *certs.CA) []reconciler.Reconciler {
 	prometheusName := ms.Name + "-prometheus"
 	alertmanagerName := ms.Name + "-alertmanager"
+	prometheusClusterName := clusterScopedName(ms, "prometheus")
+	alertmanagerClusterName := clusterScopedName(ms, "alertmanager")
 	rbacVerbs := []string{"get", "list", "watch"}
 	additionalScrapeConfigsSecretName := ms.Name + "-prometheus-additional-scrape-configs"
 	hasNsSelector := ms.Spec.NamespaceSelector != nil
 	deployAlertmanager := !ms.Spec.AlertmanagerConfig.Disabled
 
-	return []reconciler.Reconciler{
+	reconcilers := []reconciler.Reconciler{
 		// Prometheus Deployment
 		reconciler.NewUpdater(newServiceAccount(prometheusName, ms.Namespace), ms),
-		reconciler.NewUpdater(newPrometheusClusterRole(ms, prometheusName, rbacVerbs), ms),
+		reconciler.NewUpdater(newPrometheusClusterRole(ms, prometheusClusterName, rbacVerbs), ms),
 		reconciler.NewUpdater(newAdditionalScrapeConfigsSecret(ms, additionalScrapeConfigsSecretName), ms),
 		reconciler.NewUpdater(newPrometheus(ms, prometheusName,
 			additionalScrapeConfigsSecretName,
-			instanceSelectorKey, instanceSelectorValue), ms),
+			instanceSelectorKey, instanceSelectorValue, images), ms),
 		reconciler.NewUpdater(newPrometheusService(ms, instanceSelectorKey, instanceSelectorValue), ms),
 		reconciler.NewUpdater(newThanosSidecarService(ms, instanceSelectorKey, instanceSelectorValue), ms),
 		reconciler.NewOptionalUpdater(newPrometheusPDB(ms, instanceSelectorKey, instanceSelectorValue), ms,
-			*ms.Spec.PrometheusConfig.Replicas > 1),
+			prometheusReplicas(ms) > 1),
 
 		// Alertmanager Deployment
 		reconciler.NewOptionalUpdater(newServiceAccount(alertmanagerName, ms.Namespace), ms, deployAlertmanager),
 		// create clusterrolebinding if nsSelector's present otherwise a rolebinding
-		reconciler.NewOptionalUpdater(newClusterRoleBinding(ms, prometheusName), ms, hasNsSelector),
-		reconciler.NewOptionalUpdater(newRoleBindingForClusterRole(ms, prometheusName), ms, !hasNsSelector),
+		reconciler.NewOptionalUpdater(newClusterRoleBinding(ms, prometheusName, prometheusClusterName), ms, hasNsSelector),
+		reconciler.NewOptionalUpdater(newRoleBindingForClusterRole(ms, prometheusName, prometheusClusterName), ms, !hasNsSelector),
 
-		reconciler.NewOptionalUpdater(newAlertManagerClusterRole(ms, alertmanagerName, rbacVerbs), ms, deployAlertmanager),
+		reconciler.NewOptionalUpdater(newAlertManagerClusterRole(ms, alertmanagerClusterName, rbacVerbs), ms, deployAlertmanager),
 
 		// create clusterrolebinding if alertmanager is enabled and namespace selector is also present in MonitoringStack
-		reconciler.NewOptionalUpdater(newClusterRoleBinding(ms, alertmanagerName), ms, deployAlertmanager && hasNsSelector),
-		reconciler.NewOptionalUpdater(newRoleBindingForClusterRole(ms, alertmanagerName), ms, deployAlertmanager && !hasNsSelector),
+		reconciler.NewOptionalUpdater(newClusterRoleBinding(ms, alertmanagerName, alertmanagerClusterName), ms, deployAlertmanager && hasNsSelector),
+		reconciler.NewOptionalUpdater(newRoleBindingForClusterRole(ms, alertmanagerName, alertmanagerClusterName), ms, deployAlertmanager && !hasNsSelector),
 
-		reconciler.NewOptionalUpdater(newAlertmanager(ms, alertmanagerName, instanceSelectorKey, instanceSelectorValue), ms, deployAlertmanager),
+		reconciler.NewOptionalUpdater(newAlertmanager(ms, alertmanagerName, instanceSelectorKey, instanceSelectorValue, images.alertmanager), ms, deployAlertmanager),
 		reconciler.NewOptionalUpdater(newAlertmanagerService(ms, instanceSelectorKey, instanceSelectorValue), ms, deployAlertmanager),
-		reconciler.NewOptionalUpdater(newAlertmanagerPDB(ms, instanceSelectorKey, instanceSelectorValue), ms, deployAlertmanager),
+		reconciler.NewOptionalUpdater(newAlertmanagerPDB(ms, instanceSelectorKey, instanceSelectorValue), ms,
+			deployAlertmanager && alertmanagerReplicas(ms) > 1),
 	}
+	reconcilers = append(reconcilers, tlsReconcilers(ms, instanceSelectorKey, instanceSelectorValue, ca)...)
+	return append(reconcilers, exposeReconcilers(ms, instanceSelectorKey, instanceSelectorValue, routesSupported, ca)...)
+}
+
+// stackClusterScopedDeleters returns the reconcilers that remove the
+// cluster-scoped resources of a stack. These resources cannot have an owner
+// reference to the namespaced MonitoringStack and must be deleted explicitly.
+func stackClusterScopedDeleters(ms *stack.MonitoringStack) []reconciler.Reconciler {
+	prometheusName := ms.Name + "-prometheus"
+	alertmanagerName := ms.Name + "-alertmanager"
+	prometheusClusterName := clusterScopedName(ms, "prometheus")
+	alertmanagerClusterName := clusterScopedName(ms, "alertmanager")
+
+	return []reconciler.Reconciler{
+		reconciler.NewDeleter(newClusterRoleBinding(ms, prometheusName, prometheusClusterName)),
+		reconciler.NewDeleter(newPrometheusClusterRole(ms, prometheusClusterName, nil)),
+		reconciler.NewDeleter(newClusterRoleBinding(ms, alertmanagerName, alertmanagerClusterName)),
+		reconciler.NewDeleter(newAlertManagerClusterRole(ms, alertmanagerClusterName, nil)),
+	}
+}
+
+// clusterScopedName returns a deterministic name for a cluster-scoped resource
+// of a stack. The name is qualified with the namespace of the stack and ends
+// with a hash of the namespace, name and component, so that stacks with the
+// same name in different namespaces never share a resource. Names exceeding
+// maxClusterScopedNameLength are truncated before the hash is appended.
+func clusterScopedName(ms *stack.MonitoringStack, component string) string {
+	h := fnv.New32a()
+	// fnv's Write never returns an error
+	_, _ = h.Write([]byte(ms.Namespace + "/" + ms.Name + "/" + component))
+	suffix := fmt.Sprintf("-%08x", h.Sum32())
+
+	prefix := ms.Namespace + "-" + ms.Name + "-" + component
+	if len(prefix)+len(suffix) > maxClusterScopedNameLength {
+		prefix = strings.TrimRight(prefix[:maxClusterScopedNameLength-len(suffix)], "-.")
+	}
+	return prefix + suffix
 }
 
 func newPrometheusClusterRole(ms *stack.MonitoringStack, rbacResourceName string, rbacVerbs []string) *rbacv1.ClusterRole {
@@ -100,16 +157,30 @@ func newServiceAccount(name string, namespace string) *corev1.ServiceAccount {
 	}
 }
 
+// prometheusReplicas returns the number of Prometheus replicas of the stack,
+// defaulting to 2 when not set.
+func prometheusReplicas(ms *stack.MonitoringStack) int32 {
+	if ms.Spec.PrometheusConfig == nil || ms.Spec.PrometheusConfig.Replicas == nil {
+		return 2
+	}
+	return *ms.Spec.PrometheusConfig.Replicas
+}
+
 func newPrometheus(
 	ms *stack.MonitoringStack,
 	rbacResourceName string,
 	additionalScrapeConfigsSecretName string,
 	instanceSelectorKey string,
 	instanceSelectorValue string,
+	images componentImages,
 ) *monv1.Prometheus {
 	prometheusSelector := ms.Spec.ResourceSelector
 
 	config := ms.Spec.PrometheusConfig
+	if config == nil {
+		config = &stack.PrometheusConfig{}
+	}
+	replicas := prometheusReplicas(ms)
 
 	prometheus := &monv1.Prometheus{
 		TypeMeta: metav1.TypeMeta{
@@ -124,7 +195,7 @@ func newPrometheus(
 
 		Spec: monv1.PrometheusSpec{
 			CommonPrometheusFields: monv1.CommonPrometheusFields{
-				Replicas: config.Replicas,
+				Replicas: &replicas,
 
 				PodMetadata: &monv1.EmbeddedObjectMetadata{
 					Labels: podLabels("prometheus", ms.Name),
@@ -135,6 +206,9 @@ func newPrometheus(
 
 				Resources: ms.Spec.Resources,
 
+				ExternalURL: externalURL(exposedPrometheus(ms)),
+				RoutePrefix: routePrefix(exposedPrometheus(ms)),
+
 				ServiceAccountName: rbacResourceName,
 
 				ServiceMonitorSelector:          prometheusSelector,
@@ -145,18 +219,7 @@ func newPrometheus(
 				ProbeNamespaceSelector:          ms.Spec.NamespaceSelector,
 				ScrapeConfigSelector:            prometheusSelector,
 				ScrapeConfigNamespaceSelector:   ms.Spec.NamespaceSelector,
-				Affinity: &corev1.Affinity{
-					PodAntiAffinity: &corev1.PodAntiAffinity{
-						RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
-							{
-								TopologyKey: "kubernetes.io/hostname",
-								LabelSelector: &metav1.LabelSelector{
-									MatchLabels: podLabels("prometheus", ms.Name),
-								},
-							},
-						},
-					},
-				},
+				Affinity:                        newAffinity(config.Scheduling, newPodAntiAffinity("prometheus", ms.Name, config.Scheduling)),
 
 				// Prometheus should be configured for self-scraping through a static job.
 				// It avoids the need to synthesize a ServiceMonitor with labels that will match
@@ -181,8 +244,9 @@ func newPrometheus(
 			RuleSelector:          prometheusSelector,
 			RuleNamespaceSelector: ms.Spec.NamespaceSelector,
 			Thanos: &monv1.ThanosSpec{
-				BaseImage: stringPtr("quay.io/thanos/thanos"),
-				Version:   stringPtr("v0.24.0"),
+				Image:               stringPtr(thanosSidecarImage(ms, images.thanos)),
+				Resources:           thanosSidecarResources(ms),
+				ObjectStorageConfig: objectStorageConfig(ms),
 			},
 		},
 	}
@@ -196,18 +260,143 @@ func newPrometheus(
 					Namespace:  ms.Namespace,
 					Scheme:     "http",
 					Port:       intstr.FromString("web"),
+					PathPrefix: routePrefix(exposedAlertmanager(ms)),
 				},
 			},
 		}
 	}
 
+	if secretName := tlsSecretName(ms); secretName != "" {
+		prometheus.Spec.Secrets = []string{secretName}
+		prometheus.Spec.Web = &monv1.PrometheusWebSpec{
+			WebConfigFileFields: monv1.WebConfigFileFields{
+				TLSConfig: webTLSConfig(secretName),
+			},
+		}
+		// the sidecar requires client certificates signed by the CA of the stack
+		prometheus.Spec.Thanos.GRPCServerTLSConfig = fileTLSConfig(secretName, "")
+		if prometheus.Spec.Alerting != nil {
+			prometheus.Spec.Alerting.Alertmanagers[0].Scheme = "https"
+			prometheus.Spec.Alerting.Alertmanagers[0].TLSConfig = fileTLSConfig(secretName, serviceServerName(ms, "alertmanager"))
+		}
+	}
+
 	if config.ScrapeInterval != nil {
-		prometheus.Spec.ScrapeInterval = *ms.Spec.PrometheusConfig.ScrapeInterval
+		prometheus.Spec.ScrapeInterval = *config.ScrapeInterval
+	}
+
+	if scheduling := config.Scheduling; scheduling != nil {
+		prometheus.Spec.NodeSelector = scheduling.NodeSelector
+		prometheus.Spec.Tolerations = scheduling.Tolerations
+		prometheus.Spec.TopologySpreadConstraints = scheduling.TopologySpreadConstraints
+		prometheus.Spec.PriorityClassName = scheduling.PriorityClassName
+	}
+
+	if images.prometheus != "" {
+		prometheus.Spec.Image = stringPtr(images.prometheus)
+	}
+
+	if resources := configReloaderResources(ms); resources != nil {
+		prometheus.Spec.Containers = []corev1.Container{configReloaderPatch("config-reloader", *resources)}
+		prometheus.Spec.InitContainers = []corev1.Container{configReloaderPatch("init-config-reloader", *resources)}
 	}
 
 	return prometheus
 }
 
+// newAffinity returns the affinity of the scheduling config with the terms of
+// podAntiAffinity added to its pod anti-affinity.
+func newAffinity(scheduling *stack.SchedulingConfig, podAntiAffinity *corev1.PodAntiAffinity) *corev1.Affinity {
+	affinity := &corev1.Affinity{}
+	if scheduling != nil && scheduling.Affinity != nil {
+		affinity = scheduling.Affinity.DeepCopy()
+	}
+	if affinity.PodAntiAffinity == nil {
+		affinity.PodAntiAffinity = podAntiAffinity
+		return affinity
+	}
+	affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+		affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+		podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution...,
+	)
+	affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+		affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+		podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution...,
+	)
+	return affinity
+}
+
+// newPodAntiAffinity returns the pod anti-affinity which spreads the replicas
+// of a component across nodes, either as a requirement or as a preference
+// depending on the scheduling config of the component.
+func newPodAntiAffinity(component string, msName string, scheduling *stack.SchedulingConfig) *corev1.PodAntiAffinity {
+	term := corev1.PodAffinityTerm{
+		TopologyKey: "kubernetes.io/hostname",
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: podLabels(component, msName),
+		},
+	}
+
+	if scheduling != nil && scheduling.PodAntiAffinity == stack.PreferredPodAntiAffinity {
+		return &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{
+					Weight:          100,
+					PodAffinityTerm: term,
+				},
+			},
+		}
+	}
+
+	return &corev1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{term},
+	}
+}
+
+// thanosSidecarImage returns the Thanos sidecar image of the stack, which
+// overrides the image configured for the operator.
+func thanosSidecarImage(ms *stack.MonitoringStack, thanosImage string) string {
+	if ms.Spec.ThanosSidecarConfig != nil && ms.Spec.ThanosSidecarConfig.Image != "" {
+		return ms.Spec.ThanosSidecarConfig.Image
+	}
+	return thanosImage
+}
+
+// thanosSidecarResources returns the resources of the Thanos sidecar of the
+// stack or the default resources if none are set.
+func thanosSidecarResources(ms *stack.MonitoringStack) corev1.ResourceRequirements {
+	if ms.Spec.ThanosSidecarConfig != nil && !reflect.DeepEqual(ms.Spec.ThanosSidecarConfig.Resources, corev1.ResourceRequirements{}) {
+		return ms.Spec.ThanosSidecarConfig.Resources
+	}
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("10m"),
+			corev1.ResourceMemory: resource.MustParse("64Mi"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("256Mi"),
+		},
+	}
+}
+
+// configReloaderResources returns the resources of the config reloader
+// containers of the stack or nil if Prometheus Operator defaults apply.
+func configReloaderResources(ms *stack.MonitoringStack) *corev1.ResourceRequirements {
+	if ms.Spec.ConfigReloaderConfig == nil || reflect.DeepEqual(ms.Spec.ConfigReloaderConfig.Resources, corev1.ResourceRequirements{}) {
+		return nil
+	}
+	return &ms.Spec.ConfigReloaderConfig.Resources
+}
+
+// configReloaderPatch returns a container which is merged by Prometheus
+// Operator into the config reloader container with the given name.
+func configReloaderPatch(name string, resources corev1.ResourceRequirements) corev1.Container {
+	return corev1.Container{
+		Name:      name,
+		Resources: resources,
+	}
+}
+
 func storageForPVC(pvc *corev1.PersistentVolumeClaimSpec) *monv1.StorageSpec {
 	if pvc == nil {
 		return nil
@@ -224,7 +413,7 @@ func storageForPVC(pvc *corev1.PersistentVolumeClaimSpec) *monv1.StorageSpec {
 	}
 }
 
-func newRoleBindingForClusterRole(ms *stack.MonitoringStack, rbacResourceName string) *rbacv1.RoleBinding {
+func newRoleBindingForClusterRole(ms *stack.MonitoringStack, rbacResourceName string, clusterRoleName string) *rbacv1.RoleBinding {
 	roleBinding := &rbacv1.RoleBinding{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: rbacv1.SchemeGroupVersion.String(),
@@ -243,20 +432,20 @@ func newRoleBindingForClusterRole(ms *stack.MonitoringStack, rbacResourceName st
 		RoleRef: rbacv1.RoleRef{
 			APIGroup: rbacv1.SchemeGroupVersion.Group,
 			Kind:     "ClusterRole",
-			Name:     rbacResourceName,
+			Name:     clusterRoleName,
 		},
 	}
 	return roleBinding
 }
 
-func newClusterRoleBinding(ms *stack.MonitoringStack, rbacResourceName string) *rbacv1.ClusterRoleBinding {
+func newClusterRoleBinding(ms *stack.MonitoringStack, rbacResourceName string, clusterRoleName string) *rbacv1.ClusterRoleBinding {
 	roleBinding := &rbacv1.ClusterRoleBinding{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: rbacv1.SchemeGroupVersion.String(),
 			Kind:       "ClusterRoleBinding",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name: rbacResourceName,
+			Name: clusterRoleName,
 		},
 		Subjects: []rbacv1.Subject{{
 			APIGroup:  corev1.SchemeGroupVersion.Group,
@@ -267,7 +456,7 @@ func newClusterRoleBinding(ms *stack.MonitoringStack, rbacResourceName string) *
 		RoleRef: rbacv1.RoleRef{
 			APIGroup: rbacv1.SchemeGroupVersion.Group,
 			Kind:     "ClusterRole",
-			Name:     rbacResourceName,
+			Name:     clusterRoleName,
 		},
 	}
 	return roleBinding
@@ -344,6 +533,7 @@ func newAdditionalScrapeConfigsSecret(ms *stack.MonitoringStack, name string) *c
 			AdditionalScrapeConfigsSelfScrapeKey: `
 - job_name: prometheus-self
   honor_labels: true
+  metrics_path: ` + routePrefix(exposedPrometheus(ms)) + `/metrics` + scrapeTLSConfig(ms, "prometheus") + `
   relabel_configs:
   - action: keep
     source_labels:
@@ -376,8 +566,7 @@ func newAdditionalScrapeConfigsSecret(ms *stack.MonitoringStack, name string) *c
   honor_timestamps: true
   scrape_interval: 30s
   scrape_timeout: 10s
-  metrics_path: /metrics
-  scheme: http
+  metrics_path: ` + routePrefix(exposedAlertmanager(ms)) + `/metrics` + scrapeTLSConfig(ms, "alertmanager") + `
   follow_redirects: true
   relabel_configs:
   - source_labels:
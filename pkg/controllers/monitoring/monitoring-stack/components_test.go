@@ -1,7 +1,14 @@
 package monitoringstack
 
 import (
+	"strings"
 	"testing"
+	"time"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certs"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 
@@ -9,6 +16,9 @@ import (
 	v1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
 	"gotest.tools/v3/assert"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
 )
 
 func TestStorageSpec(t *testing.T) {
@@ -43,3 +53,312 @@ func TestStorageSpec(t *testing.T) {
 		assert.DeepEqual(t, tc.expected, actual)
 	}
 }
+
+func TestClusterScopedName(t *testing.T) {
+	newStack := func(namespace, name string) *stack.MonitoringStack {
+		return &stack.MonitoringStack{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+
+	name := clusterScopedName(newStack("ns", "stack"), "prometheus")
+	assert.Assert(t, strings.HasPrefix(name, "ns-stack-prometheus-"), name)
+	assert.Equal(t, name, clusterScopedName(newStack("ns", "stack"), "prometheus"))
+
+	// stacks with the same name in different namespaces must not collide,
+	// including namespaces and names which concatenate to the same string
+	assert.Assert(t, name != clusterScopedName(newStack("other", "stack"), "prometheus"))
+	assert.Assert(t, clusterScopedName(newStack("a-b", "c"), "prometheus") !=
+		clusterScopedName(newStack("a", "b-c"), "prometheus"))
+
+	long := newStack(strings.Repeat("n", 63), strings.Repeat("s", 63))
+	longName := clusterScopedName(long, "alertmanager")
+	assert.Equal(t, len(longName), maxClusterScopedNameLength)
+	assert.Assert(t, longName != clusterScopedName(long, "prometheus"))
+
+	// truncation must not leave a separator right before the hash
+	trimmed := clusterScopedName(newStack(strings.Repeat("n", 53), "s"), "prometheus")
+	assert.Assert(t, !strings.Contains(trimmed, "--"), trimmed)
+}
+
+func TestThanosSidecarImage(t *testing.T) {
+	ms := &stack.MonitoringStack{}
+	assert.Equal(t, thanosSidecarImage(ms, "thanos:operator"), "thanos:operator")
+
+	ms.Spec.ThanosSidecarConfig = &stack.ThanosSidecarConfig{}
+	assert.Equal(t, thanosSidecarImage(ms, "thanos:operator"), "thanos:operator")
+
+	ms.Spec.ThanosSidecarConfig.Image = "mirror.example.com/thanos:stack"
+	assert.Equal(t, thanosSidecarImage(ms, "thanos:operator"), "mirror.example.com/thanos:stack")
+}
+
+func TestComponentResources(t *testing.T) {
+	ms := &stack.MonitoringStack{
+		Spec: stack.MonitoringStackSpec{
+			PrometheusConfig: &stack.PrometheusConfig{},
+		},
+	}
+	prom := newPrometheus(ms, "prometheus", "scrape-configs", "key", "value", componentImages{})
+	assert.Equal(t, prom.Spec.Thanos.Resources.Requests.Memory().String(), "64Mi")
+	assert.Equal(t, len(prom.Spec.Containers), 0)
+	assert.Equal(t, len(prom.Spec.InitContainers), 0)
+
+	custom := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("1m"),
+		},
+	}
+	ms.Spec.ThanosSidecarConfig = &stack.ThanosSidecarConfig{Resources: custom}
+	ms.Spec.ConfigReloaderConfig = &stack.ConfigReloaderConfig{Resources: custom}
+
+	prom = newPrometheus(ms, "prometheus", "scrape-configs", "key", "value", componentImages{})
+	assert.DeepEqual(t, prom.Spec.Thanos.Resources, custom)
+	assert.DeepEqual(t, prom.Spec.Containers, []corev1.Container{{Name: "config-reloader", Resources: custom}})
+	assert.DeepEqual(t, prom.Spec.InitContainers, []corev1.Container{{Name: "init-config-reloader", Resources: custom}})
+
+	am := newAlertmanager(ms, "alertmanager", "key", "value", "")
+	assert.DeepEqual(t, am.Spec.Containers, []corev1.Container{{Name: "config-reloader", Resources: custom}})
+	assert.DeepEqual(t, am.Spec.InitContainers, []corev1.Container{{Name: "init-config-reloader", Resources: custom}})
+}
+
+func TestPrometheusReplicas(t *testing.T) {
+	// the stack may not have been defaulted by the webhook
+	ms := &stack.MonitoringStack{ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "ns"}}
+	assert.Assert(t, len(stackComponentReconcilers(ms, "key", "value", componentImages{}, false, nil)) > 0)
+	prom := newPrometheus(ms, "prometheus", "scrape-configs", "key", "value", componentImages{})
+	assert.Equal(t, *prom.Spec.Replicas, int32(2))
+
+	ms.Spec.PrometheusConfig = &stack.PrometheusConfig{Replicas: pointer.Int32(1)}
+	prom = newPrometheus(ms, "prometheus", "scrape-configs", "key", "value", componentImages{})
+	assert.Equal(t, *prom.Spec.Replicas, int32(1))
+}
+
+func TestScheduling(t *testing.T) {
+	ms := &stack.MonitoringStack{
+		Spec: stack.MonitoringStackSpec{
+			PrometheusConfig: &stack.PrometheusConfig{},
+		},
+	}
+
+	// replicas are required to run on different nodes by default
+	prom := newPrometheus(ms, "prometheus", "scrape-configs", "key", "value", componentImages{})
+	assert.Equal(t, len(prom.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution), 1)
+	assert.Equal(t, len(prom.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution), 0)
+
+	am := newAlertmanager(ms, "alertmanager", "key", "value", "")
+	assert.Equal(t, len(am.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution), 1)
+	assert.Equal(t, len(am.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution), 1)
+
+	scheduling := &stack.SchedulingConfig{
+		NodeSelector:      map[string]string{"node-role.kubernetes.io/infra": ""},
+		Tolerations:       []corev1.Toleration{{Key: "infra", Operator: corev1.TolerationOpExists}},
+		PriorityClassName: "system-cluster-critical",
+		PodAntiAffinity:   stack.PreferredPodAntiAffinity,
+	}
+	ms.Spec.PrometheusConfig.Scheduling = scheduling
+	ms.Spec.AlertmanagerConfig.Scheduling = scheduling
+
+	prom = newPrometheus(ms, "prometheus", "scrape-configs", "key", "value", componentImages{})
+	assert.Equal(t, len(prom.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution), 0)
+	assert.Equal(t, len(prom.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution), 1)
+	assert.DeepEqual(t, prom.Spec.NodeSelector, scheduling.NodeSelector)
+	assert.DeepEqual(t, prom.Spec.Tolerations, scheduling.Tolerations)
+	assert.Equal(t, prom.Spec.PriorityClassName, scheduling.PriorityClassName)
+
+	am = newAlertmanager(ms, "alertmanager", "key", "value", "")
+	assert.Equal(t, len(am.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution), 0)
+	assert.Equal(t, len(am.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution), 2)
+	assert.DeepEqual(t, am.Spec.NodeSelector, scheduling.NodeSelector)
+	assert.Equal(t, am.Spec.PriorityClassName, scheduling.PriorityClassName)
+
+	// the anti-affinity terms are added to the affinity of the user
+	scheduling.Affinity = &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{},
+		},
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{TopologyKey: "rack"}},
+		},
+	}
+	prom = newPrometheus(ms, "prometheus", "scrape-configs", "key", "value", componentImages{})
+	assert.Assert(t, prom.Spec.Affinity.NodeAffinity != nil)
+	assert.Equal(t, len(prom.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution), 1)
+	assert.Equal(t, len(prom.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution), 1)
+	assert.Equal(t, len(scheduling.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution), 0)
+}
+
+func TestExpose(t *testing.T) {
+	ms := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "ns"},
+		Spec: stack.MonitoringStackSpec{
+			PrometheusConfig: &stack.PrometheusConfig{},
+		},
+	}
+
+	// nothing is exposed by default
+	for _, r := range exposeReconcilers(ms, "key", "value", false, nil) {
+		_, ok := r.(reconciler.Deleter)
+		assert.Assert(t, ok)
+	}
+	prom := newPrometheus(ms, "prometheus", "scrape-configs", "key", "value", componentImages{})
+	assert.Equal(t, prom.Spec.ExternalURL, "")
+	assert.Equal(t, prom.Spec.RoutePrefix, "")
+
+	ms.Spec.Web = &stack.WebConfig{
+		Expose: &stack.ExposeConfig{
+			Prometheus: &stack.ExposedEndpoint{
+				Host:          "monitoring.example.com",
+				Path:          "/prometheus/",
+				TLSSecretName: "monitoring-tls",
+			},
+			Alertmanager: &stack.ExposedEndpoint{Path: "/"},
+		},
+	}
+
+	prom = newPrometheus(ms, "prometheus", "scrape-configs", "key", "value", componentImages{})
+	assert.Equal(t, prom.Spec.ExternalURL, "https://monitoring.example.com/prometheus")
+	assert.Equal(t, prom.Spec.RoutePrefix, "/prometheus")
+	assert.Equal(t, prom.Spec.Alerting.Alertmanagers[0].PathPrefix, "")
+
+	am := newAlertmanager(ms, "alertmanager", "key", "value", "")
+	assert.Equal(t, am.Spec.ExternalURL, "")
+	assert.Equal(t, am.Spec.RoutePrefix, "")
+
+	reconcilers := exposeReconcilers(ms, "key", "value", false, nil)
+	assert.Equal(t, len(reconcilers), 2)
+	for _, r := range reconcilers {
+		_, ok := r.(reconciler.Updater)
+		assert.Assert(t, ok)
+	}
+
+	ingress := newIngress(ms, "stack-prometheus", *ms.Spec.Web.Expose.Prometheus, "key", "value")
+	rule := ingress.Spec.Rules[0]
+	assert.Equal(t, rule.Host, "monitoring.example.com")
+	assert.Equal(t, rule.HTTP.Paths[0].Path, "/prometheus")
+	assert.Equal(t, rule.HTTP.Paths[0].Backend.Service.Name, "stack-prometheus")
+	assert.DeepEqual(t, ingress.Spec.TLS, []networkingv1.IngressTLS{{
+		Hosts:      []string{"monitoring.example.com"},
+		SecretName: "monitoring-tls",
+	}})
+
+	// routes replace ingresses when the Route API is available
+	reconcilers = exposeReconcilers(ms, "key", "value", true, nil)
+	assert.Equal(t, len(reconcilers), 4)
+	for i, r := range reconcilers {
+		_, isUpdater := r.(reconciler.Updater)
+		assert.Equal(t, isUpdater, i%2 == 1)
+	}
+
+	route := newRoute(ms, "stack-prometheus", *ms.Spec.Web.Expose.Prometheus, "key", "value", nil)
+	assert.Equal(t, route.GetKind(), "Route")
+	host, _, _ := unstructured.NestedString(route.Object, "spec", "host")
+	assert.Equal(t, host, "monitoring.example.com")
+	path, _, _ := unstructured.NestedString(route.Object, "spec", "path")
+	assert.Equal(t, path, "/prometheus")
+	secret, _, _ := unstructured.NestedString(route.Object, "spec", "tls", "externalCertificate", "name")
+	assert.Equal(t, secret, "monitoring-tls")
+
+	// disabled alertmanager isn't exposed
+	ms.Spec.AlertmanagerConfig.Disabled = true
+	reconcilers = exposeReconcilers(ms, "key", "value", false, nil)
+	_, ok := reconcilers[1].(reconciler.Deleter)
+	assert.Assert(t, ok)
+}
+
+func TestTLS(t *testing.T) {
+	ms := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "ns"},
+		Spec: stack.MonitoringStackSpec{
+			PrometheusConfig: &stack.PrometheusConfig{},
+		},
+	}
+
+	// TLS is disabled by default
+	prom := newPrometheus(ms, "prometheus", "scrape-configs", "key", "value", componentImages{})
+	assert.Assert(t, prom.Spec.Web == nil)
+	assert.Assert(t, prom.Spec.Thanos.GRPCServerTLSConfig == nil)
+	assert.Equal(t, prom.Spec.Alerting.Alertmanagers[0].Scheme, "http")
+	reconcilers := tlsReconcilers(ms, "key", "value", nil)
+	assert.Equal(t, len(reconcilers), 1)
+	_, ok := reconcilers[0].(reconciler.Deleter)
+	assert.Assert(t, ok)
+
+	// the operator provisions the certificate when no secret is referenced
+	ms.Spec.TLS = &stack.TLSConfig{}
+	reconcilers = tlsReconcilers(ms, "key", "value", nil)
+	assert.Equal(t, len(reconcilers), 1)
+	_, ok = reconcilers[0].(certs.CertificateUpdater)
+	assert.Assert(t, ok)
+
+	prom = newPrometheus(ms, "prometheus", "scrape-configs", "key", "value", componentImages{})
+	assert.DeepEqual(t, prom.Spec.Secrets, []string{"stack-tls"})
+	assert.Equal(t, prom.Spec.Web.TLSConfig.Cert.Secret.Name, "stack-tls")
+	assert.Equal(t, prom.Spec.Web.TLSConfig.KeySecret.Key, "tls.key")
+	assert.DeepEqual(t, prom.Spec.Thanos.GRPCServerTLSConfig, &monv1.TLSConfig{
+		CAFile:   "/etc/prometheus/secrets/stack-tls/ca.crt",
+		CertFile: "/etc/prometheus/secrets/stack-tls/tls.crt",
+		KeyFile:  "/etc/prometheus/secrets/stack-tls/tls.key",
+	})
+	alertmanager := prom.Spec.Alerting.Alertmanagers[0]
+	assert.Equal(t, alertmanager.Scheme, "https")
+	assert.Equal(t, alertmanager.TLSConfig.ServerName, "stack-alertmanager.ns.svc")
+
+	am := newAlertmanager(ms, "alertmanager", "key", "value", "")
+	assert.Equal(t, am.Spec.Web.TLSConfig.Cert.Secret.Name, "stack-tls")
+
+	scrapeConfigs := newAdditionalScrapeConfigsSecret(ms, "scrape-configs").StringData[AdditionalScrapeConfigsSelfScrapeKey]
+	assert.Equal(t, strings.Count(scrapeConfigs, "scheme: https"), 2)
+	assert.Assert(t, strings.Contains(scrapeConfigs, "server_name: stack-prometheus.ns.svc"))
+	assert.Assert(t, strings.Contains(scrapeConfigs, "server_name: stack-alertmanager.ns.svc"))
+
+	// referenced secrets are used as they are
+	ms.Spec.TLS.CertificateSecretName = "custom-tls"
+	prom = newPrometheus(ms, "prometheus", "scrape-configs", "key", "value", componentImages{})
+	assert.DeepEqual(t, prom.Spec.Secrets, []string{"custom-tls"})
+	reconcilers = tlsReconcilers(ms, "key", "value", nil)
+	assert.Equal(t, len(reconcilers), 1)
+	_, ok = reconcilers[0].(reconciler.Deleter)
+	assert.Assert(t, ok)
+
+	ms.Spec.TLS.CertificateSecretName = "stack-tls"
+	assert.Equal(t, len(tlsReconcilers(ms, "key", "value", nil)), 0)
+}
+
+func TestGrafanaDataSource(t *testing.T) {
+	ms := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "ns"},
+	}
+
+	ds := newGrafanaDataSource(ms, "key", "value", nil)
+	datasources, _, err := unstructured.NestedSlice(ds.Object, "spec", "datasources")
+	assert.NilError(t, err)
+	assert.Equal(t, datasources[0].(map[string]interface{})["url"], "http://stack-prometheus.ns.svc:9090")
+
+	ca, err := certs.NewCA("ca", time.Now())
+	assert.NilError(t, err)
+	ms.Spec.TLS = &stack.TLSConfig{}
+	ds = newGrafanaDataSource(ms, "key", "value", ca)
+	datasources, _, err = unstructured.NestedSlice(ds.Object, "spec", "datasources")
+	assert.NilError(t, err)
+	datasource := datasources[0].(map[string]interface{})
+	assert.Equal(t, datasource["url"], "https://stack-prometheus.ns.svc:9090")
+	caCert, _, err := unstructured.NestedString(datasource, "secureJsonData", "tlsCACert")
+	assert.NilError(t, err)
+	assert.Equal(t, caCert, string(ca.CertPEM()))
+
+	// the operator doesn't know the CA of referenced secrets
+	ms.Spec.TLS.CertificateSecretName = "custom-tls"
+	ds = newGrafanaDataSource(ms, "key", "value", ca)
+	datasources, _, err = unstructured.NestedSlice(ds.Object, "spec", "datasources")
+	assert.NilError(t, err)
+	assert.Assert(t, datasources[0].(map[string]interface{})["secureJsonData"] == nil)
+
+	// Prometheus serves its API under the path it is exposed at
+	ms.Spec.Web = &stack.WebConfig{Expose: &stack.ExposeConfig{
+		Prometheus: &stack.ExposedEndpoint{Host: "stack.example.com", Path: "/prometheus/"},
+	}}
+	ms.Spec.PrometheusConfig = &stack.PrometheusConfig{}
+	ds = newGrafanaDataSource(ms, "key", "value", ca)
+	datasources, _, err = unstructured.NestedSlice(ds.Object, "spec", "datasources")
+	assert.NilError(t, err)
+	assert.Equal(t, datasources[0].(map[string]interface{})["url"], "https://stack-prometheus.ns.svc:9090/prometheus")
+	assert.Equal(t, newPrometheus(ms, "prometheus", "scrape-configs", "key", "value", componentImages{}).Spec.RoutePrefix, "/prometheus")
+}
@@ -5,6 +5,8 @@ import (
 
 	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -12,6 +14,7 @@ const (
 	AvailableReason                = "MonitoringStackAvailable"
 	ReconciledReason               = "MonitoringStackReconciled"
 	FailedToReconcileReason        = "FailedToReconcile"
+	FailedToCleanupReason          = "FailedToCleanup"
 	PrometheusNotAvailable         = "PrometheusNotAvailable"
 	PrometheusNotReconciled        = "PrometheusNotReconciled"
 	PrometheusDegraded             = "PrometheusDegraded"
@@ -21,15 +24,100 @@ const (
 	SuccessfullyReconciledMessage  = "Monitoring Stack is successfully reconciled"
 	ResourceSelectorIsNilMessage   = "No resources will be discovered, ResourceSelector is nil"
 	ResourceDiscoveryOnMessage     = "Resource discovery is operational"
+	NoResourcesMatchedReason       = "NoResourcesMatched"
+	NoResourcesMatchedMessage      = "No ServiceMonitors, PodMonitors, Probes, PrometheusRules, ScrapeConfigs or AlertmanagerConfigs match the selectors"
+	CannotDiscoverResourcesReason  = "CannotDiscoverResources"
 	NoReason                       = "None"
+	UploadsSucceededReason         = "UploadsSucceeded"
+	UploadsFailedReason            = "UploadsFailed"
+	CannotScrapeSidecarsReason     = "CannotScrapeSidecars"
+	UploadsSucceededMessage        = "Thanos sidecars upload blocks to the object storage"
+
+	PrometheusAvailableReason        = "PrometheusAvailable"
+	PrometheusAvailableMessage       = "Prometheus is available"
+	AlertmanagerAvailableReason      = "AlertmanagerAvailable"
+	AlertmanagerNotAvailable         = "AlertmanagerNotAvailable"
+	AlertmanagerDegraded             = "AlertmanagerDegraded"
+	AlertmanagerAvailableMessage     = "Alertmanager is available"
+	CannotReadAlertmanagerConditions = "Cannot read Alertmanager status conditions"
+	ThanosSidecarReadyReason         = "ThanosSidecarReady"
+	ThanosSidecarNotReady            = "ThanosSidecarNotReady"
+	CannotListPrometheusPodsReason   = "CannotListPrometheusPods"
+	NoPrometheusPodsReason           = "NoPrometheusPods"
+	ThanosSidecarReadyMessage        = "Thanos sidecars are ready"
+	NoPrometheusPodsMessage          = "No Prometheus pods are running"
+
+	thanosSidecarContainer = "thanos-sidecar"
+)
+
+// componentReasons are the reasons and messages of the availability condition
+// of a component managed by the Prometheus Operator.
+type componentReasons struct {
+	available        string
+	availableMessage string
+	notAvailable     string
+	degraded         string
+	cannotRead       string
+}
+
+var (
+	prometheusReasons = componentReasons{
+		available:        PrometheusAvailableReason,
+		availableMessage: PrometheusAvailableMessage,
+		notAvailable:     PrometheusNotAvailable,
+		degraded:         PrometheusDegraded,
+		cannotRead:       CannotReadPrometheusConditions,
+	}
+	alertmanagerReasons = componentReasons{
+		available:        AlertmanagerAvailableReason,
+		availableMessage: AlertmanagerAvailableMessage,
+		notAvailable:     AlertmanagerNotAvailable,
+		degraded:         AlertmanagerDegraded,
+		cannotRead:       CannotReadAlertmanagerConditions,
+	}
 )
 
-func updateConditions(ms *v1alpha1.MonitoringStack, prom monv1.Prometheus, recError error) []v1alpha1.Condition {
-	return []v1alpha1.Condition{
-		updateResourceDiscovery(ms),
-		updateAvailable(ms.Status.Conditions, prom, ms.Generation),
+// updateConditions returns the conditions of the stack. am is nil when
+// Alertmanager is disabled. pods are the Prometheus pods of the stack, unless
+// listing them failed with podsErr. discovered are the resources matching the
+// selectors of the stack, unless counting them failed with discoveryErr.
+func updateConditions(ms *v1alpha1.MonitoringStack, prom monv1.Prometheus, am *monv1.Alertmanager, pods []corev1.Pod, podsErr error, discovered *v1alpha1.DiscoveredResources, discoveryErr error, recError error) []v1alpha1.Condition {
+	components := []v1alpha1.Condition{
+		updatePrometheusAvailable(ms.Status.Conditions, prom, ms.Generation),
+	}
+	if am != nil {
+		components = append(components, updateAlertmanagerAvailable(ms.Status.Conditions, *am, ms.Generation))
+	}
+	components = append(components, updateThanosSidecarReady(ms.Status.Conditions, pods, podsErr, ms.Generation))
+
+	conditions := []v1alpha1.Condition{
+		updateResourceDiscovery(ms, discovered, discoveryErr),
+		updateAvailable(ms.Status.Conditions, ms.Generation, components...),
 		updateReconciled(ms.Status.Conditions, prom, ms.Generation, recError),
 	}
+	return append(conditions, components...)
+}
+
+// cleanupFailedConditions returns the stack conditions with the "Reconciled"
+// condition set to false because the cluster-scoped resources of a stack
+// being deleted could not be removed.
+func cleanupFailedConditions(ms *v1alpha1.MonitoringStack, cleanupErr error) []v1alpha1.Condition {
+	rc := v1alpha1.Condition{
+		Type:               v1alpha1.ReconciledCondition,
+		Status:             v1alpha1.ConditionFalse,
+		Reason:             FailedToCleanupReason,
+		Message:            cleanupErr.Error(),
+		LastTransitionTime: metav1.Now(),
+		ObservedGeneration: ms.Generation,
+	}
+
+	conditions := []v1alpha1.Condition{}
+	for _, c := range ms.Status.Conditions {
+		if c.Type != v1alpha1.ReconciledCondition {
+			conditions = append(conditions, c)
+		}
+	}
+	return append(conditions, rc)
 }
 
 func getMSCondition(conditions []v1alpha1.Condition, t v1alpha1.ConditionType) (v1alpha1.Condition, error) {
@@ -42,78 +130,203 @@ func getMSCondition(conditions []v1alpha1.Condition, t v1alpha1.ConditionType) (
 }
 
 // updateResourceDiscovery updates the ResourceDiscoveryCondition based on the
-// ResourceSelector in the MonitorinStack spec. A ResourceSelector of nil causes
-// the condition to be false, any other value sets the condition to true
-func updateResourceDiscovery(ms *v1alpha1.MonitoringStack) v1alpha1.Condition {
-	if ms.Spec.ResourceSelector == nil {
-		return v1alpha1.Condition{
-			Type:               v1alpha1.ResourceDiscoveryCondition,
-			Status:             v1alpha1.ConditionFalse,
-			Reason:             ResourceSelectorIsNil,
-			Message:            ResourceSelectorIsNilMessage,
-			LastTransitionTime: metav1.Now(),
-			ObservedGeneration: ms.Generation,
-		}
-	} else {
-		return v1alpha1.Condition{
-			Type:               v1alpha1.ResourceDiscoveryCondition,
-			Status:             v1alpha1.ConditionTrue,
+// ResourceSelector in the MonitorinStack spec and the resources matching it.
+// A ResourceSelector of nil or a selector matching no resources causes the
+// condition to be false, any other value sets the condition to true
+func updateResourceDiscovery(ms *v1alpha1.MonitoringStack, discovered *v1alpha1.DiscoveredResources, discoveryErr error) v1alpha1.Condition {
+	rc := v1alpha1.Condition{
+		Type:               v1alpha1.ResourceDiscoveryCondition,
+		Status:             v1alpha1.ConditionTrue,
+		Reason:             NoReason,
+		Message:            ResourceDiscoveryOnMessage,
+		LastTransitionTime: metav1.Now(),
+		ObservedGeneration: ms.Generation,
+	}
+	switch {
+	case ms.Spec.ResourceSelector == nil:
+		rc.Status = v1alpha1.ConditionFalse
+		rc.Reason = ResourceSelectorIsNil
+		rc.Message = ResourceSelectorIsNilMessage
+	case discoveryErr != nil:
+		rc.Status = v1alpha1.ConditionUnknown
+		rc.Reason = CannotDiscoverResourcesReason
+		rc.Message = discoveryErr.Error()
+	case discovered != nil && *discovered == v1alpha1.DiscoveredResources{}:
+		rc.Status = v1alpha1.ConditionFalse
+		rc.Reason = NoResourcesMatchedReason
+		rc.Message = NoResourcesMatchedMessage
+	}
+	return rc
+}
+
+// updateAvailable returns the "Available" condition of the stack, which is
+// the conjunction of the availability conditions of its components. The
+// reason and message of an unavailable stack are the ones of the first
+// component responsible for it.
+func updateAvailable(conditions []v1alpha1.Condition, generation int64, components ...v1alpha1.Condition) v1alpha1.Condition {
+	ac, err := getMSCondition(conditions, v1alpha1.AvailableCondition)
+	if err != nil {
+		ac = v1alpha1.Condition{
+			Type:               v1alpha1.AvailableCondition,
+			Status:             v1alpha1.ConditionUnknown,
 			Reason:             NoReason,
-			Message:            ResourceDiscoveryOnMessage,
 			LastTransitionTime: metav1.Now(),
-			ObservedGeneration: ms.Generation,
 		}
 	}
 
+	var unknown, notAvailable *v1alpha1.Condition
+	for i := range components {
+		switch components[i].Status {
+		case v1alpha1.ConditionFalse:
+			if notAvailable == nil {
+				notAvailable = &components[i]
+			}
+		case v1alpha1.ConditionUnknown:
+			if unknown == nil {
+				unknown = &components[i]
+			}
+		}
+	}
+
+	switch {
+	case notAvailable != nil:
+		ac.Status = v1alpha1.ConditionFalse
+		ac.Reason = notAvailable.Reason
+		ac.Message = notAvailable.Message
+	case unknown != nil:
+		ac.Status = v1alpha1.ConditionUnknown
+		ac.Reason = unknown.Reason
+		ac.Message = unknown.Message
+	default:
+		ac.Status = v1alpha1.ConditionTrue
+		ac.Reason = AvailableReason
+		ac.Message = AvailableMessage
+		ac.ObservedGeneration = generation
+	}
+	ac.LastTransitionTime = metav1.Now()
+	return ac
+}
+
+// updatePrometheusAvailable returns the "PrometheusAvailable" condition based
+// on the Prometheus "Available" condition.
+func updatePrometheusAvailable(conditions []v1alpha1.Condition, prom monv1.Prometheus, generation int64) v1alpha1.Condition {
+	return updateComponentAvailable(conditions, v1alpha1.PrometheusAvailableCondition,
+		prom.Status.Conditions, prom.Generation, generation, prometheusReasons)
 }
 
-// updateAvailable gets existing "Available" condition and updates its parameters
-// based on the Prometheus "Available" condition
-func updateAvailable(conditions []v1alpha1.Condition, prom monv1.Prometheus, generation int64) v1alpha1.Condition {
-	ac, err := getMSCondition(conditions, v1alpha1.AvailableCondition)
+// updateAlertmanagerAvailable returns the "AlertmanagerAvailable" condition
+// based on the Alertmanager "Available" condition.
+func updateAlertmanagerAvailable(conditions []v1alpha1.Condition, am monv1.Alertmanager, generation int64) v1alpha1.Condition {
+	return updateComponentAvailable(conditions, v1alpha1.AlertmanagerAvailableCondition,
+		am.Status.Conditions, am.Generation, generation, alertmanagerReasons)
+}
+
+// updateComponentAvailable gets the existing condition of type t and updates
+// its parameters based on the "Available" condition of a component managed by
+// the Prometheus Operator.
+func updateComponentAvailable(conditions []v1alpha1.Condition, t v1alpha1.ConditionType, componentConditions []monv1.Condition, componentGeneration int64, generation int64, reasons componentReasons) v1alpha1.Condition {
+	ac, err := getMSCondition(conditions, t)
 	if err != nil {
 		ac = v1alpha1.Condition{
-			Type:               v1alpha1.AvailableCondition,
+			Type:               t,
 			Status:             v1alpha1.ConditionUnknown,
 			Reason:             NoReason,
 			LastTransitionTime: metav1.Now(),
 		}
 	}
 
-	prometheusAvailable, err := getPrometheusCondition(prom.Status.Conditions, monv1.Available)
+	componentAvailable, err := getPrometheusCondition(componentConditions, monv1.Available)
 
 	if err != nil {
 		ac.Status = v1alpha1.ConditionUnknown
-		ac.Reason = PrometheusNotAvailable
-		ac.Message = CannotReadPrometheusConditions
+		ac.Reason = reasons.notAvailable
+		ac.Message = reasons.cannotRead
 		ac.LastTransitionTime = metav1.Now()
 		return ac
 	}
-	// MonitoringStack status will not be updated if there is a difference between the Prometheus generation
-	// and the Prometheus ObservedGeneration. This can occur, for example, in the case of an invalid Prometheus configuration.
-	if prometheusAvailable.ObservedGeneration != prom.Generation {
+	// The condition will not be updated if there is a difference between the generation of the component
+	// and its ObservedGeneration. This can occur, for example, in the case of an invalid configuration.
+	if componentAvailable.ObservedGeneration != componentGeneration {
 		return ac
 	}
 
-	if prometheusAvailable.Status != monv1.ConditionTrue {
-		ac.Status = prometheusStatusToMSStatus(prometheusAvailable.Status)
-		if prometheusAvailable.Status == monv1.ConditionDegraded {
-			ac.Reason = PrometheusDegraded
+	if componentAvailable.Status != monv1.ConditionTrue {
+		ac.Status = prometheusStatusToMSStatus(componentAvailable.Status)
+		if componentAvailable.Status == monv1.ConditionDegraded {
+			ac.Reason = reasons.degraded
 		} else {
-			ac.Reason = PrometheusNotAvailable
+			ac.Reason = reasons.notAvailable
 		}
-		ac.Message = prometheusAvailable.Message
+		ac.Message = componentAvailable.Message
 		ac.LastTransitionTime = metav1.Now()
 		return ac
 	}
 	ac.Status = v1alpha1.ConditionTrue
-	ac.Reason = AvailableReason
-	ac.Message = AvailableMessage
+	ac.Reason = reasons.available
+	ac.Message = reasons.availableMessage
 	ac.ObservedGeneration = generation
 	ac.LastTransitionTime = metav1.Now()
 	return ac
 }
 
+// updateThanosSidecarReady returns the "ThanosSidecarReady" condition based
+// on the readiness of the Thanos sidecar containers of the Prometheus pods.
+func updateThanosSidecarReady(conditions []v1alpha1.Condition, pods []corev1.Pod, podsErr error, generation int64) v1alpha1.Condition {
+	sc, err := getMSCondition(conditions, v1alpha1.ThanosSidecarReadyCondition)
+	if err != nil {
+		sc = v1alpha1.Condition{
+			Type:               v1alpha1.ThanosSidecarReadyCondition,
+			Status:             v1alpha1.ConditionUnknown,
+			Reason:             NoReason,
+			LastTransitionTime: metav1.Now(),
+		}
+	}
+	sc.ObservedGeneration = generation
+	sc.LastTransitionTime = metav1.Now()
+
+	if podsErr != nil {
+		sc.Status = v1alpha1.ConditionUnknown
+		sc.Reason = CannotListPrometheusPodsReason
+		sc.Message = podsErr.Error()
+		return sc
+	}
+
+	total, notReady := 0, 0
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		total++
+		if !containerReady(pod, thanosSidecarContainer) {
+			notReady++
+		}
+	}
+	switch {
+	case total == 0:
+		sc.Status = v1alpha1.ConditionUnknown
+		sc.Reason = NoPrometheusPodsReason
+		sc.Message = NoPrometheusPodsMessage
+	case notReady > 0:
+		sc.Status = v1alpha1.ConditionFalse
+		sc.Reason = ThanosSidecarNotReady
+		sc.Message = fmt.Sprintf("%d of %d Thanos sidecars are not ready", notReady, total)
+	default:
+		sc.Status = v1alpha1.ConditionTrue
+		sc.Reason = ThanosSidecarReadyReason
+		sc.Message = ThanosSidecarReadyMessage
+	}
+	return sc
+}
+
+func containerReady(pod corev1.Pod, name string) bool {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name == name {
+			return cs.Ready
+		}
+	}
+	return false
+}
+
 // updateReconciled updates "Reconciled" conditions based on the provided error value and
 // Prometheus "Reconciled" condition
 func updateReconciled(conditions []v1alpha1.Condition, prom monv1.Prometheus, generation int64, reconcileErr error) v1alpha1.Condition {
@@ -162,6 +375,54 @@ func updateReconciled(conditions []v1alpha1.Condition, prom monv1.Prometheus, ge
 	return rc
 }
 
+// updateObjectStorageUpload returns the "ObjectStorageUpload" condition based
+// on the uploads of the Thanos sidecars. The condition is false when uploads
+// failed since the previous status of the stack was reported, and unchanged
+// when the uploads are the ones of the previous status.
+func updateObjectStorageUpload(conditions []v1alpha1.Condition, previous *v1alpha1.ObjectStorageStatus, current *v1alpha1.ObjectStorageStatus, scrapeErr error, generation int64) v1alpha1.Condition {
+	oc, err := getMSCondition(conditions, v1alpha1.ObjectStorageUploadCondition)
+	if err != nil {
+		oc = v1alpha1.Condition{
+			Type:               v1alpha1.ObjectStorageUploadCondition,
+			Status:             v1alpha1.ConditionUnknown,
+			Reason:             NoReason,
+			LastTransitionTime: metav1.Now(),
+		}
+	}
+	oc.ObservedGeneration = generation
+	// the stack is reconciled again after its status is written, so the
+	// condition of uploads which were already reported is kept
+	if err == nil && scrapeErr == nil && oc.Reason != CannotScrapeSidecarsReason && equality.Semantic.DeepEqual(previous, current) {
+		return oc
+	}
+
+	if scrapeErr != nil {
+		oc.Status = v1alpha1.ConditionUnknown
+		oc.Reason = CannotScrapeSidecarsReason
+		oc.Message = scrapeErr.Error()
+		oc.LastTransitionTime = metav1.Now()
+		return oc
+	}
+
+	failures := current.UploadFailures
+	// the counters of the sidecars are reset when they restart
+	if previous != nil && previous.UploadFailures <= current.UploadFailures {
+		failures -= previous.UploadFailures
+	}
+	if failures > 0 {
+		oc.Status = v1alpha1.ConditionFalse
+		oc.Reason = UploadsFailedReason
+		oc.Message = fmt.Sprintf("%d uploads of the Thanos sidecars failed", failures)
+		oc.LastTransitionTime = metav1.Now()
+		return oc
+	}
+	oc.Status = v1alpha1.ConditionTrue
+	oc.Reason = UploadsSucceededReason
+	oc.Message = UploadsSucceededMessage
+	oc.LastTransitionTime = metav1.Now()
+	return oc
+}
+
 func getPrometheusCondition(prometheusConditions []monv1.Condition, t monv1.ConditionType) (*monv1.Condition, error) {
 	for _, c := range prometheusConditions {
 		if c.Type == t {
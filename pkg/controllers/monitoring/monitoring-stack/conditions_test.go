@@ -1,15 +1,101 @@
 package monitoringstack
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
 	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
 	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestUpdateAvailable(t *testing.T) {
+	prometheusAvailable := v1alpha1.Condition{
+		Type:    v1alpha1.PrometheusAvailableCondition,
+		Status:  v1alpha1.ConditionTrue,
+		Reason:  PrometheusAvailableReason,
+		Message: PrometheusAvailableMessage,
+	}
+	alertmanagerDegraded := v1alpha1.Condition{
+		Type:    v1alpha1.AlertmanagerAvailableCondition,
+		Status:  v1alpha1.ConditionFalse,
+		Reason:  AlertmanagerDegraded,
+		Message: "1 of 2 pods is ready",
+	}
+	sidecarUnknown := v1alpha1.Condition{
+		Type:    v1alpha1.ThanosSidecarReadyCondition,
+		Status:  v1alpha1.ConditionUnknown,
+		Reason:  NoPrometheusPodsReason,
+		Message: NoPrometheusPodsMessage,
+	}
+	sidecarReady := v1alpha1.Condition{
+		Type:    v1alpha1.ThanosSidecarReadyCondition,
+		Status:  v1alpha1.ConditionTrue,
+		Reason:  ThanosSidecarReadyReason,
+		Message: ThanosSidecarReadyMessage,
+	}
+
+	tt := []struct {
+		name           string
+		components     []v1alpha1.Condition
+		expectedResult v1alpha1.Condition
+	}{
+		{
+			name:       "all components available",
+			components: []v1alpha1.Condition{prometheusAvailable, sidecarReady},
+			expectedResult: v1alpha1.Condition{
+				Type:               v1alpha1.AvailableCondition,
+				Status:             v1alpha1.ConditionTrue,
+				ObservedGeneration: 2,
+				Reason:             AvailableReason,
+				Message:            AvailableMessage,
+			},
+		},
+		{
+			name:       "unavailable component takes precedence over unknown component",
+			components: []v1alpha1.Condition{prometheusAvailable, sidecarUnknown, alertmanagerDegraded},
+			expectedResult: v1alpha1.Condition{
+				Type:               v1alpha1.AvailableCondition,
+				Status:             v1alpha1.ConditionFalse,
+				ObservedGeneration: 1,
+				Reason:             AlertmanagerDegraded,
+				Message:            "1 of 2 pods is ready",
+			},
+		},
+		{
+			name:       "unknown component",
+			components: []v1alpha1.Condition{prometheusAvailable, sidecarUnknown},
+			expectedResult: v1alpha1.Condition{
+				Type:               v1alpha1.AvailableCondition,
+				Status:             v1alpha1.ConditionUnknown,
+				ObservedGeneration: 1,
+				Reason:             NoPrometheusPodsReason,
+				Message:            NoPrometheusPodsMessage,
+			},
+		},
+	}
+
+	previousConditions := []v1alpha1.Condition{
+		{
+			Type:               v1alpha1.AvailableCondition,
+			Status:             v1alpha1.ConditionTrue,
+			ObservedGeneration: 1,
+			Reason:             AvailableReason,
+			Message:            AvailableMessage,
+		},
+	}
+	for _, test := range tt {
+		res := updateAvailable(previousConditions, 2, test.components...)
+		assert.Check(t, test.expectedResult.Equal(res), "%s - expected:\n %v\n and got:\n %v\n", test.name, test.expectedResult, res)
+	}
+}
+
+func TestUpdatePrometheusAvailable(t *testing.T) {
 	tt := []struct {
 		name               string
 		prometheus         monv1.Prometheus
@@ -21,11 +107,11 @@ func TestUpdateAvailable(t *testing.T) {
 			name: "conditions not changed when Prometheus Available",
 			previousConditions: []v1alpha1.Condition{
 				{
-					Type:               v1alpha1.AvailableCondition,
+					Type:               v1alpha1.PrometheusAvailableCondition,
 					Status:             v1alpha1.ConditionTrue,
 					ObservedGeneration: 1,
-					Reason:             AvailableReason,
-					Message:            AvailableMessage,
+					Reason:             PrometheusAvailableReason,
+					Message:            PrometheusAvailableMessage,
 				},
 			},
 			prometheus: monv1.Prometheus{
@@ -42,28 +128,28 @@ func TestUpdateAvailable(t *testing.T) {
 					}}},
 			generation: 1,
 			expectedResult: v1alpha1.Condition{
-				Type:               v1alpha1.AvailableCondition,
+				Type:               v1alpha1.PrometheusAvailableCondition,
 				Status:             v1alpha1.ConditionTrue,
 				ObservedGeneration: 1,
-				Reason:             AvailableReason,
-				Message:            AvailableMessage,
+				Reason:             PrometheusAvailableReason,
+				Message:            PrometheusAvailableMessage,
 			},
 		},
 		{
 			name: "cannot read Prometheus conditions",
 			previousConditions: []v1alpha1.Condition{
 				{
-					Type:               v1alpha1.AvailableCondition,
+					Type:               v1alpha1.PrometheusAvailableCondition,
 					Status:             v1alpha1.ConditionTrue,
 					ObservedGeneration: 1,
-					Reason:             AvailableReason,
-					Message:            AvailableMessage,
+					Reason:             PrometheusAvailableReason,
+					Message:            PrometheusAvailableMessage,
 				},
 			},
 			generation: 1,
 			prometheus: monv1.Prometheus{},
 			expectedResult: v1alpha1.Condition{
-				Type:               v1alpha1.AvailableCondition,
+				Type:               v1alpha1.PrometheusAvailableCondition,
 				Status:             v1alpha1.ConditionUnknown,
 				ObservedGeneration: 1,
 				Reason:             PrometheusNotAvailable,
@@ -74,11 +160,11 @@ func TestUpdateAvailable(t *testing.T) {
 			name: "degraded Prometheus conditions",
 			previousConditions: []v1alpha1.Condition{
 				{
-					Type:               v1alpha1.AvailableCondition,
+					Type:               v1alpha1.PrometheusAvailableCondition,
 					Status:             v1alpha1.ConditionTrue,
 					ObservedGeneration: 1,
-					Reason:             AvailableReason,
-					Message:            AvailableMessage,
+					Reason:             PrometheusAvailableReason,
+					Message:            PrometheusAvailableMessage,
 				},
 			},
 			generation: 1,
@@ -95,7 +181,7 @@ func TestUpdateAvailable(t *testing.T) {
 						},
 					}}},
 			expectedResult: v1alpha1.Condition{
-				Type:               v1alpha1.AvailableCondition,
+				Type:               v1alpha1.PrometheusAvailableCondition,
 				Status:             v1alpha1.ConditionFalse,
 				ObservedGeneration: 1,
 				Reason:             PrometheusDegraded,
@@ -105,11 +191,11 @@ func TestUpdateAvailable(t *testing.T) {
 			name: "Prometheus observed generation is different from the Prometheus generation",
 			previousConditions: []v1alpha1.Condition{
 				{
-					Type:               v1alpha1.AvailableCondition,
+					Type:               v1alpha1.PrometheusAvailableCondition,
 					Status:             v1alpha1.ConditionTrue,
 					ObservedGeneration: 2,
-					Reason:             AvailableReason,
-					Message:            AvailableMessage,
+					Reason:             PrometheusAvailableReason,
+					Message:            PrometheusAvailableMessage,
 				},
 			},
 			generation: 1,
@@ -126,21 +212,111 @@ func TestUpdateAvailable(t *testing.T) {
 						},
 					}}},
 			expectedResult: v1alpha1.Condition{
-				Type:               v1alpha1.AvailableCondition,
+				Type:               v1alpha1.PrometheusAvailableCondition,
 				Status:             v1alpha1.ConditionTrue,
 				ObservedGeneration: 2,
-				Reason:             AvailableReason,
-				Message:            AvailableMessage,
+				Reason:             PrometheusAvailableReason,
+				Message:            PrometheusAvailableMessage,
 			},
 		},
 	}
 
 	for _, test := range tt {
-		res := updateAvailable(test.previousConditions, test.prometheus, test.generation)
+		res := updatePrometheusAvailable(test.previousConditions, test.prometheus, test.generation)
 		assert.Check(t, test.expectedResult.Equal(res), "%s - expected:\n %v\n and got:\n %v\n", test.name, test.expectedResult, res)
 	}
 }
 
+func TestUpdateAlertmanagerAvailable(t *testing.T) {
+	am := monv1.Alertmanager{
+		ObjectMeta: metav1.ObjectMeta{
+			Generation: 1,
+		},
+	}
+
+	// a missing Alertmanager has no conditions
+	res := updateAlertmanagerAvailable(nil, am, 1)
+	assert.Equal(t, res.Type, v1alpha1.AlertmanagerAvailableCondition)
+	assert.Equal(t, res.Status, v1alpha1.ConditionUnknown)
+	assert.Equal(t, res.Message, CannotReadAlertmanagerConditions)
+
+	am.Status.Conditions = []monv1.Condition{
+		{
+			Type:               monv1.Available,
+			Status:             monv1.ConditionFalse,
+			ObservedGeneration: 1,
+			Message:            "pods are crashlooping",
+		},
+	}
+	res = updateAlertmanagerAvailable([]v1alpha1.Condition{res}, am, 1)
+	assert.Equal(t, res.Status, v1alpha1.ConditionFalse)
+	assert.Equal(t, res.Reason, AlertmanagerNotAvailable)
+	assert.Equal(t, res.Message, "pods are crashlooping")
+
+	am.Status.Conditions[0].Status = monv1.ConditionTrue
+	res = updateAlertmanagerAvailable([]v1alpha1.Condition{res}, am, 1)
+	assert.Equal(t, res.Status, v1alpha1.ConditionTrue)
+	assert.Equal(t, res.Reason, AlertmanagerAvailableReason)
+}
+
+func TestUpdateThanosSidecarReady(t *testing.T) {
+	pod := func(sidecarReady bool) corev1.Pod {
+		return corev1.Pod{
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "prometheus", Ready: true},
+					{Name: thanosSidecarContainer, Ready: sidecarReady},
+				},
+			},
+		}
+	}
+
+	tt := []struct {
+		name    string
+		pods    []corev1.Pod
+		podsErr error
+		status  v1alpha1.ConditionStatus
+		reason  string
+	}{
+		{
+			name:   "all sidecars ready",
+			pods:   []corev1.Pod{pod(true), pod(true)},
+			status: v1alpha1.ConditionTrue,
+			reason: ThanosSidecarReadyReason,
+		},
+		{
+			name:   "sidecar not ready",
+			pods:   []corev1.Pod{pod(true), pod(false)},
+			status: v1alpha1.ConditionFalse,
+			reason: ThanosSidecarNotReady,
+		},
+		{
+			name:   "pending pod",
+			pods:   []corev1.Pod{pod(true), {}},
+			status: v1alpha1.ConditionFalse,
+			reason: ThanosSidecarNotReady,
+		},
+		{
+			name:   "no pods",
+			status: v1alpha1.ConditionUnknown,
+			reason: NoPrometheusPodsReason,
+		},
+		{
+			name:    "pods cannot be listed",
+			podsErr: fmt.Errorf("forbidden"),
+			status:  v1alpha1.ConditionUnknown,
+			reason:  CannotListPrometheusPodsReason,
+		},
+	}
+
+	for _, test := range tt {
+		res := updateThanosSidecarReady(nil, test.pods, test.podsErr, 1)
+		assert.Equal(t, res.Type, v1alpha1.ThanosSidecarReadyCondition, test.name)
+		assert.Equal(t, res.Status, test.status, test.name)
+		assert.Equal(t, res.Reason, test.reason, test.name)
+	}
+}
+
 func TestUpdateReconciled(t *testing.T) {
 	tt := []struct {
 		name               string
@@ -283,6 +459,8 @@ func TestUpdateResourceDiscovery(t *testing.T) {
 	tt := []struct {
 		name             string
 		msWithConditions v1alpha1.MonitoringStack
+		discovered       *v1alpha1.DiscoveredResources
+		discoveryErr     error
 		expectedResults  v1alpha1.Condition
 	}{
 		{
@@ -292,6 +470,7 @@ func TestUpdateResourceDiscovery(t *testing.T) {
 					ResourceSelector: &metav1.LabelSelector{},
 				},
 			},
+			discovered: &v1alpha1.DiscoveredResources{ServiceMonitors: 1},
 			expectedResults: v1alpha1.Condition{
 				Type:    v1alpha1.ResourceDiscoveryCondition,
 				Status:  v1alpha1.ConditionTrue,
@@ -306,6 +485,7 @@ func TestUpdateResourceDiscovery(t *testing.T) {
 					ResourceSelector: nil,
 				},
 			},
+			discovered: &v1alpha1.DiscoveredResources{AlertmanagerConfigs: 1},
 			expectedResults: v1alpha1.Condition{
 				Type:               v1alpha1.ResourceDiscoveryCondition,
 				Status:             v1alpha1.ConditionFalse,
@@ -314,11 +494,178 @@ func TestUpdateResourceDiscovery(t *testing.T) {
 				LastTransitionTime: transitionTime,
 			},
 		},
+		{
+			name: "set resource discovery false when no resources match",
+			msWithConditions: v1alpha1.MonitoringStack{
+				Spec: v1alpha1.MonitoringStackSpec{
+					ResourceSelector: &metav1.LabelSelector{},
+				},
+			},
+			discovered: &v1alpha1.DiscoveredResources{},
+			expectedResults: v1alpha1.Condition{
+				Type:    v1alpha1.ResourceDiscoveryCondition,
+				Status:  v1alpha1.ConditionFalse,
+				Reason:  NoResourcesMatchedReason,
+				Message: NoResourcesMatchedMessage,
+			},
+		},
+		{
+			name: "set resource discovery unknown when resources cannot be counted",
+			msWithConditions: v1alpha1.MonitoringStack{
+				Spec: v1alpha1.MonitoringStackSpec{
+					ResourceSelector: &metav1.LabelSelector{},
+				},
+			},
+			discoveryErr: fmt.Errorf("forbidden"),
+			expectedResults: v1alpha1.Condition{
+				Type:    v1alpha1.ResourceDiscoveryCondition,
+				Status:  v1alpha1.ConditionUnknown,
+				Reason:  CannotDiscoverResourcesReason,
+				Message: "forbidden",
+			},
+		},
 	}
 
 	for _, test := range tt {
-		res := updateResourceDiscovery(&test.msWithConditions)
+		res := updateResourceDiscovery(&test.msWithConditions, test.discovered, test.discoveryErr)
 		assert.Check(t, test.expectedResults.Equal(res), "%s - expected:\n %v\n and got:\n %v\n", test.name, test.expectedResults, res)
 	}
 
 }
+
+func TestCleanupFailedConditions(t *testing.T) {
+	ms := v1alpha1.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{
+			Generation: 2,
+		},
+		Status: v1alpha1.MonitoringStackStatus{
+			Conditions: []v1alpha1.Condition{
+				{
+					Type:               v1alpha1.AvailableCondition,
+					Status:             v1alpha1.ConditionTrue,
+					ObservedGeneration: 2,
+					Reason:             AvailableReason,
+					Message:            AvailableMessage,
+				},
+				{
+					Type:               v1alpha1.ReconciledCondition,
+					Status:             v1alpha1.ConditionTrue,
+					ObservedGeneration: 2,
+					Reason:             ReconciledReason,
+					Message:            SuccessfullyReconciledMessage,
+				},
+			},
+		},
+	}
+
+	res := cleanupFailedConditions(&ms, fmt.Errorf("forbidden"))
+	assert.Equal(t, len(res), 2)
+
+	available, err := getMSCondition(res, v1alpha1.AvailableCondition)
+	assert.NilError(t, err)
+	assert.Check(t, ms.Status.Conditions[0].Equal(available))
+
+	reconciled, err := getMSCondition(res, v1alpha1.ReconciledCondition)
+	assert.NilError(t, err)
+	expected := v1alpha1.Condition{
+		Type:               v1alpha1.ReconciledCondition,
+		Status:             v1alpha1.ConditionFalse,
+		ObservedGeneration: 2,
+		Reason:             FailedToCleanupReason,
+		Message:            "forbidden",
+	}
+	assert.Check(t, expected.Equal(reconciled), "expected:\n %v\n and got:\n %v\n", expected, reconciled)
+}
+
+func TestUpdateObjectStorageUpload(t *testing.T) {
+	tt := []struct {
+		name           string
+		previous       *v1alpha1.ObjectStorageStatus
+		current        *v1alpha1.ObjectStorageStatus
+		scrapeErr      error
+		expectedStatus v1alpha1.ConditionStatus
+		expectedReason string
+	}{
+		{
+			name:           "first report without failures",
+			current:        &v1alpha1.ObjectStorageStatus{Uploads: 2},
+			expectedStatus: v1alpha1.ConditionTrue,
+			expectedReason: UploadsSucceededReason,
+		},
+		{
+			name:           "failures since previous report",
+			previous:       &v1alpha1.ObjectStorageStatus{Uploads: 2, UploadFailures: 1},
+			current:        &v1alpha1.ObjectStorageStatus{Uploads: 2, UploadFailures: 3},
+			expectedStatus: v1alpha1.ConditionFalse,
+			expectedReason: UploadsFailedReason,
+		},
+		{
+			name:           "no failures since previous report",
+			previous:       &v1alpha1.ObjectStorageStatus{Uploads: 2, UploadFailures: 3},
+			current:        &v1alpha1.ObjectStorageStatus{Uploads: 4, UploadFailures: 3},
+			expectedStatus: v1alpha1.ConditionTrue,
+			expectedReason: UploadsSucceededReason,
+		},
+		{
+			name:           "failures after the sidecars restarted",
+			previous:       &v1alpha1.ObjectStorageStatus{Uploads: 2, UploadFailures: 3},
+			current:        &v1alpha1.ObjectStorageStatus{UploadFailures: 1},
+			expectedStatus: v1alpha1.ConditionFalse,
+			expectedReason: UploadsFailedReason,
+		},
+		{
+			name:           "sidecars cannot be scraped",
+			previous:       &v1alpha1.ObjectStorageStatus{Uploads: 2},
+			scrapeErr:      fmt.Errorf("connection refused"),
+			expectedStatus: v1alpha1.ConditionUnknown,
+			expectedReason: CannotScrapeSidecarsReason,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := updateObjectStorageUpload(nil, tc.previous, tc.current, tc.scrapeErr, 1)
+			assert.Equal(t, c.Type, v1alpha1.ObjectStorageUploadCondition)
+			assert.Equal(t, c.Status, tc.expectedStatus)
+			assert.Equal(t, c.Reason, tc.expectedReason)
+			assert.Equal(t, c.ObservedGeneration, int64(1))
+		})
+	}
+
+	// reporting the same uploads again keeps the failures
+	uploads := &v1alpha1.ObjectStorageStatus{Uploads: 2, UploadFailures: 3}
+	failed := updateObjectStorageUpload(nil, &v1alpha1.ObjectStorageStatus{Uploads: 2, UploadFailures: 1}, uploads, nil, 1)
+	c := updateObjectStorageUpload([]v1alpha1.Condition{failed}, uploads, uploads.DeepCopy(), nil, 1)
+	assert.Equal(t, c.Status, v1alpha1.ConditionFalse)
+	assert.Equal(t, c.Reason, UploadsFailedReason)
+}
+
+func TestGetAlertmanager(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NilError(t, monv1.AddToScheme(scheme))
+
+	ms := &v1alpha1.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "ns"},
+	}
+	am := newAlertmanager(ms, "stack-alertmanager", "key", "value", "alertmanager")
+	am.Generation = 1
+	am.Status.Conditions = []monv1.Condition{
+		{
+			Type:               monv1.Available,
+			Status:             monv1.ConditionTrue,
+			ObservedGeneration: 1,
+		},
+	}
+	rm := resourceManager{k8sClient: fake.NewClientBuilder().WithScheme(scheme).WithObjects(am).Build()}
+
+	got, err := rm.getAlertmanager(context.Background(), ms)
+	assert.NilError(t, err)
+	assert.Equal(t, got.Name, am.Name)
+	res := updateAlertmanagerAvailable(nil, *got, 1)
+	assert.Equal(t, res.Status, v1alpha1.ConditionTrue)
+
+	ms.Spec.AlertmanagerConfig.Disabled = true
+	got, err = rm.getAlertmanager(context.Background(), ms)
+	assert.NilError(t, err)
+	assert.Assert(t, got == nil)
+}
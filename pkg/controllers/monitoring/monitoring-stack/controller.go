@@ -23,8 +23,8 @@ import (
 	"time"
 
 	"sigs.k8s.io/controller-runtime/pkg/builder"
-	"sigs.k8s.io/controller-runtime/pkg/controller"
 
+	networkingv1 "k8s.io/api/networking/v1"
 	policyv1 "k8s.io/api/policy/v1"
 
 	v1 "k8s.io/api/core/v1"
@@ -32,11 +32,21 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certs"
+	"github.com/rhobs/observability-operator/pkg/conditions"
+	"github.com/rhobs/observability-operator/pkg/grafana"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
 
 	"github.com/go-logr/logr"
 	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
@@ -44,29 +54,71 @@ import (
 
 type resourceManager struct {
 	k8sClient             client.Client
+	apiReader             client.Reader
 	scheme                *runtime.Scheme
 	logger                logr.Logger
+	recorder              record.EventRecorder
+	events                reconciler.EventRecorder
 	instanceSelectorKey   string
 	instanceSelectorValue string
-	grafanaDSWatchCreated bool
-	controller            controller.Controller
+	images                componentImages
+	routesSupported       bool
+	caProvider            *certs.CAProvider
+	uploads               *uploadScraper
+	discoveries           *discoveryThrottle
+	grafanaDataSources    *grafana.DataSourceWatcher
 }
 
+// stackFinalizer is added to every MonitoringStack so that the cluster-scoped
+// resources created for it, which cannot be garbage collected through owner
+// references, are removed before the stack is deleted.
+const stackFinalizer = "monitoring.rhobs/cleanup"
+
+// resourceDiscoveryFailedReason is the reason of the events recorded when the
+// resources selected by a stack can't be discovered, e.g. due to an invalid
+// selector.
+const resourceDiscoveryFailedReason = "ResourceDiscoveryFailed"
+
 // Options allows for controller options to be set
 type Options struct {
 	InstanceSelector string
+	// PrometheusImage is the container image of Prometheus
+	PrometheusImage string
+	// AlertmanagerImage is the container image of Alertmanager
+	AlertmanagerImage string
+	// ThanosImage is the container image of the Thanos sidecar
+	ThanosImage string
+	// CAProvider provides the CA signing the certificates of stacks with TLS
+	CAProvider *certs.CAProvider
 }
 
 // RBAC for managing monitoring stacks
 //+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks,verbs=list;watch;create;update
 //+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks/status,verbs=get;update
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks/finalizers,verbs=update
 
 // RBAC for managing Prometheus Operator CRs
 //+kubebuilder:rbac:groups=monitoring.rhobs,resources=alertmanagers;prometheuses;servicemonitors,verbs=list;watch;create;update;delete;patch
-//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings;clusterroles;clusterrolebindings,verbs=list;watch;create;update;delete;patch
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings;clusterroles;clusterrolebindings,verbs=get;list;watch;create;update;delete;patch
 //+kubebuilder:rbac:groups="",resources=serviceaccounts;services;secrets,verbs=list;watch;create;update;delete;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
 //+kubebuilder:rbac:groups="policy",resources=poddisruptionbudgets,verbs=list;watch;create;update;delete;patch
 
+// RBAC for managing Grafana datasources
+//+kubebuilder:rbac:groups=integreatly.org,resources=grafanadatasources,verbs=get;list;watch;create;update;delete;patch
+
+// RBAC for counting the resources discovered by Prometheus and Alertmanager
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=podmonitors;probes;prometheusrules;scrapeconfigs;alertmanagerconfigs,verbs=list
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=list;watch
+
+// RBAC for recording events
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// RBAC for exposing Prometheus and Alertmanager
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=list;watch;create;update;delete;patch
+//+kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=list;watch;create;update;delete;patch
+//+kubebuilder:rbac:groups=route.openshift.io,resources=routes/custom-host,verbs=create;update;patch
+
 // RBAC for delegating permissions to Prometheus
 //+kubebuilder:rbac:groups="",resources=pods;services;endpoints,verbs=get;list;watch
 //+kubebuilder:rbac:groups=extensions;networking.k8s.io,resources=ingresses,verbs=get;list;watch
@@ -81,41 +133,74 @@ func RegisterWithManager(mgr ctrl.Manager, opts Options) error {
 		return fmt.Errorf("invalid InstanceSelector: %s", opts.InstanceSelector)
 	}
 
+	routesSupported, err := isRouteAPIAvailable(mgr.GetRESTMapper())
+	if err != nil {
+		return err
+	}
+
+	recorder := mgr.GetEventRecorderFor("observability-operator")
 	rm := &resourceManager{
 		k8sClient:             mgr.GetClient(),
+		apiReader:             mgr.GetAPIReader(),
 		scheme:                mgr.GetScheme(),
 		logger:                ctrl.Log.WithName("observability-operator"),
+		recorder:              recorder,
+		events:                reconciler.NewEventRecorder(recorder),
 		instanceSelectorKey:   split[0],
 		instanceSelectorValue: split[1],
-		grafanaDSWatchCreated: false,
+		images: componentImages{
+			prometheus:   opts.PrometheusImage,
+			alertmanager: opts.AlertmanagerImage,
+			thanos:       opts.ThanosImage,
+		},
+		routesSupported: routesSupported,
+		caProvider:      opts.CAProvider,
+		discoveries:     newDiscoveryThrottle(),
 	}
+	rm.uploads = newUploadScraper(rm.k8sClient, rm.prometheusPods, rm.logger.WithName("upload-scraper"))
 	// We only want to trigger a reconciliation when the generation
 	// of a child changes. Until we need to update our the status for our own objects,
 	// we can save CPU cycles by avoiding reconciliations triggered by
-	// child status changes. The only exception is Prometheus resources, where we want to
-	// be notified about changes in their status.
+	// child status changes. The only exceptions are Prometheus and Alertmanager resources,
+	// where we want to be notified about changes in their status.
 	generationChanged := builder.WithPredicates(predicate.GenerationChangedPredicate{})
 
-	ctrl, err := ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&stack.MonitoringStack{}).
 		Owns(&monv1.Prometheus{}, builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
-		Owns(&monv1.Alertmanager{}, generationChanged).
+		Owns(&monv1.Alertmanager{}, builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
 		Owns(&v1.Service{}, generationChanged).
 		Owns(&v1.ServiceAccount{}, generationChanged).
 		Owns(&rbacv1.Role{}, generationChanged).
 		Owns(&rbacv1.RoleBinding{}, generationChanged).
 		Owns(&monv1.ServiceMonitor{}, generationChanged).
 		Owns(&policyv1.PodDisruptionBudget{}, generationChanged).
-		Build(rm)
+		Owns(&networkingv1.Ingress{}, generationChanged).
+		// the uploads of the sidecars are scraped outside of reconciliations
+		Watches(&source.Channel{Source: rm.uploads.events}, &handler.EnqueueRequestForObject{})
+	if routesSupported {
+		b = b.Owns(newRouteObject(), generationChanged)
+	}
+
+	ctrl, err := b.Build(rm)
 
 	if err != nil {
 		return err
 	}
-	rm.controller = ctrl
-	return nil
+	rm.grafanaDataSources = grafana.NewDataSourceWatcher(mgr.GetRESTMapper(), ctrl, &stack.MonitoringStack{}, rm.logger)
+
+	if err := mgr.Add(rm.uploads); err != nil {
+		return err
+	}
+
+	// Cluster-scoped resources used to be named after the stack only, which
+	// made stacks with the same name in different namespaces overwrite each
+	// other's resources. The resources created with the old names are removed
+	// once when the operator starts.
+	return mgr.Add(manager.RunnableFunc(rm.removeLegacyResources))
 }
 
-func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (rm *resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := rm.logger.WithValues("stack", req.NamespacedName)
 	logger.Info("Reconciling monitoring stack")
 	ms, err := rm.getStack(ctx, req)
@@ -130,13 +215,45 @@ func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	}
 
 	if !ms.ObjectMeta.DeletionTimestamp.IsZero() {
-		logger.V(6).Info("skipping reconcile since object is already schedule for deletion")
-		return ctrl.Result{}, nil
+		return rm.cleanup(ctx, req, ms)
 	}
 
-	reconcilers := stackComponentReconcilers(ms, rm.instanceSelectorKey, rm.instanceSelectorValue)
+	if !controllerutil.ContainsFinalizer(ms, stackFinalizer) {
+		patch := finalizerPatch(ms)
+		controllerutil.AddFinalizer(ms, stackFinalizer)
+		if err := rm.k8sClient.Patch(ctx, ms, patch); err != nil {
+			if errors.IsConflict(err) {
+				logger.V(3).Info("skipping reconcile error", "err", err)
+				return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+			}
+			return ctrl.Result{}, err
+		}
+	}
+
+	var ca *certs.CA
+	if provisionsCertificate(ms) {
+		ca, err = rm.caProvider.CA(ctx)
+		if errors.IsAlreadyExists(err) || errors.IsConflict(err) {
+			logger.V(3).Info("skipping reconcile error", "err", err)
+			return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+		}
+		if err != nil {
+			return rm.updateStatus(ctx, req, ms, err), err
+		}
+	}
+
+	reconcilers := stackComponentReconcilers(ms, rm.instanceSelectorKey, rm.instanceSelectorValue, rm.images, rm.routesSupported, ca)
+
+	grafanaSupported, err := rm.grafanaDataSources.EnsureWatch()
+	if err != nil {
+		return rm.updateStatus(ctx, req, ms, err), err
+	}
+	if grafanaSupported {
+		reconcilers = append(reconcilers, reconciler.NewUpdater(
+			newGrafanaDataSource(ms, rm.instanceSelectorKey, rm.instanceSelectorValue, ca), ms))
+	}
 	for _, reconciler := range reconcilers {
-		err := reconciler.Reconcile(ctx, rm.k8sClient, rm.scheme)
+		err := rm.events.Reconcile(ctx, rm.k8sClient, rm.scheme, ms, reconciler)
 		// handle create / update errors that can happen due to a stale cache by
 		// retrying after some time.
 		if errors.IsAlreadyExists(err) || errors.IsConflict(err) {
@@ -151,9 +268,51 @@ func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	return rm.updateStatus(ctx, req, ms, nil), nil
 }
 
+// cleanup removes the cluster-scoped resources of a MonitoringStack that is
+// being deleted and releases the finalizer once all of them are gone.
+func (rm resourceManager) cleanup(ctx context.Context, req ctrl.Request, ms *stack.MonitoringStack) (ctrl.Result, error) {
+	logger := rm.logger.WithValues("stack", req.NamespacedName)
+	if !controllerutil.ContainsFinalizer(ms, stackFinalizer) {
+		logger.V(6).Info("skipping reconcile since object is already schedule for deletion")
+		return ctrl.Result{}, nil
+	}
+
+	logger.Info("Cleaning up cluster-scoped resources of monitoring stack")
+	for _, deleter := range stackClusterScopedDeleters(ms) {
+		if err := rm.events.Reconcile(ctx, rm.k8sClient, rm.scheme, ms, deleter); err != nil {
+			ms.Status.Conditions = conditions.Merge(ms.Status.Conditions, cleanupFailedConditions(ms, err))
+			if statusErr := rm.k8sClient.Status().Update(ctx, ms); statusErr != nil {
+				logger.Info("Failed to update status", "err", statusErr)
+			}
+			return ctrl.Result{}, err
+		}
+	}
+
+	patch := finalizerPatch(ms)
+	controllerutil.RemoveFinalizer(ms, stackFinalizer)
+	if err := rm.k8sClient.Patch(ctx, ms, patch); err != nil {
+		if errors.IsConflict(err) || errors.IsNotFound(err) {
+			logger.V(3).Info("skipping reconcile error", "err", err)
+			return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	rm.discoveries.forget(req.NamespacedName)
+	return ctrl.Result{}, nil
+}
+
+// finalizerPatch returns the patch adding or removing the finalizer of ms.
+// Only the metadata of the stack is patched, rather than updating the whole
+// stack, and the resource version prevents overwriting concurrent changes to
+// the finalizers.
+func finalizerPatch(ms *stack.MonitoringStack) client.Patch {
+	return client.MergeFromWithOptions(ms.DeepCopy(), client.MergeFromWithOptimisticLock{})
+}
+
 func (rm resourceManager) updateStatus(ctx context.Context, req ctrl.Request, ms *stack.MonitoringStack, recError error) ctrl.Result {
 	var prom monv1.Prometheus
 	logger := rm.logger.WithValues("stack", req.NamespacedName)
+	previous := ms.Status.DeepCopy()
 	key := client.ObjectKey{
 		Name:      ms.Name,
 		Namespace: ms.Namespace,
@@ -163,13 +322,79 @@ func (rm resourceManager) updateStatus(ctx context.Context, req ctrl.Request, ms
 		logger.Info("Failed to get prometheus object", "err", err)
 		return ctrl.Result{RequeueAfter: 2 * time.Second}
 	}
-	ms.Status.Conditions = updateConditions(ms, prom, recError)
-	err = rm.k8sClient.Status().Update(ctx, ms)
+
+	am, err := rm.getAlertmanager(ctx, ms)
+	if err != nil {
+		logger.Info("Failed to get alertmanager object", "err", err)
+		return ctrl.Result{RequeueAfter: 2 * time.Second}
+	}
+
+	pods, podsErr := rm.prometheusPods(ctx, ms)
+	if podsErr != nil {
+		logger.Info("Failed to list prometheus pods", "err", podsErr)
+	}
+	// the previous counts are reported until the resources are counted again
+	discovered, discoveryErr := ms.Status.DiscoveredResources, error(nil)
+	if now := time.Now(); rm.discoveries.due(ms, now) {
+		discovered, discoveryErr = rm.discoveredResources(ctx, ms)
+		if discoveryErr != nil {
+			logger.Info("Failed to count discovered resources", "err", discoveryErr)
+			rm.recorder.Event(ms, v1.EventTypeWarning, resourceDiscoveryFailedReason, discoveryErr.Error())
+		} else {
+			ms.Status.DiscoveredResources = discovered
+			rm.discoveries.counted(ms, now)
+		}
+	}
+	updated := updateConditions(ms, prom, am, pods, podsErr, discovered, discoveryErr, recError)
+
+	// the discovered resources are reported periodically since they don't
+	// trigger any reconciliation
+	result := ctrl.Result{RequeueAfter: resourceDiscoveryResyncInterval}
+	if ms.Spec.ObjectStorage != nil {
+		uploads, err, scraped := rm.uploads.get(client.ObjectKeyFromObject(ms))
+		switch {
+		case !scraped:
+			// keep the previous condition until the sidecars are scraped
+			if c, err := getMSCondition(ms.Status.Conditions, stack.ObjectStorageUploadCondition); err == nil {
+				updated = append(updated, c)
+			}
+		case err != nil:
+			logger.Info("Failed to get object storage uploads", "err", err)
+			updated = append(updated, updateObjectStorageUpload(ms.Status.Conditions, ms.Status.ObjectStorage, nil, err, ms.Generation))
+		default:
+			updated = append(updated, updateObjectStorageUpload(ms.Status.Conditions, ms.Status.ObjectStorage, uploads, nil, ms.Generation))
+			ms.Status.ObjectStorage = uploads
+		}
+	} else {
+		ms.Status.ObjectStorage = nil
+	}
+
+	ms.Status.Conditions = conditions.Merge(previous.Conditions, updated)
+	err = conditions.UpdateStatus(ctx, rm.k8sClient, rm.recorder, ms, previous, &ms.Status, previous.Conditions, ms.Status.Conditions)
 	if err != nil {
 		logger.Info("Failed to update status", "err", err)
 		return ctrl.Result{RequeueAfter: 2 * time.Second}
 	}
-	return ctrl.Result{}
+	return result
+}
+
+// getAlertmanager returns the Alertmanager of the stack, which is nil when
+// Alertmanager is disabled. A missing Alertmanager is returned empty so that
+// it is reported through its condition.
+func (rm resourceManager) getAlertmanager(ctx context.Context, ms *stack.MonitoringStack) (*monv1.Alertmanager, error) {
+	if ms.Spec.AlertmanagerConfig.Disabled {
+		return nil, nil
+	}
+	am := &monv1.Alertmanager{}
+	// the Alertmanager is named after the stack, like the Prometheus
+	key := client.ObjectKey{
+		Name:      ms.Name,
+		Namespace: ms.Namespace,
+	}
+	if err := rm.k8sClient.Get(ctx, key, am); client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+	return am, nil
 }
 
 func (rm resourceManager) getStack(ctx context.Context, req ctrl.Request) (*stack.MonitoringStack, error) {
@@ -188,3 +413,16 @@ func (rm resourceManager) getStack(ctx context.Context, req ctrl.Request) (*stac
 
 	return &ms, nil
 }
+
+// isRouteAPIAvailable returns whether the OpenShift Route API is served by
+// the cluster.
+func isRouteAPIAvailable(mapper meta.RESTMapper) (bool, error) {
+	_, err := mapper.RESTMapping(routeGVK.GroupKind(), routeGVK.Version)
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to discover the Route API: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,173 @@
+package monitoringstack
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	monv1alpha1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// resourceDiscoveryResyncInterval is the interval at which the resources
+// matching the selectors of a stack are counted again, since changes of
+// these resources don't trigger any reconciliation.
+const resourceDiscoveryResyncInterval = 5 * time.Minute
+
+// discoveryThrottle records when the resources selected by each stack were
+// counted, so that they are counted at most once per resync interval unless
+// the spec of the stack changes. Counting lists the resources of the whole
+// cluster when a namespace selector is set.
+type discoveryThrottle struct {
+	mu          sync.Mutex
+	discoveries map[types.NamespacedName]discovery
+}
+
+// discovery is a successful count of the resources selected by a stack.
+type discovery struct {
+	generation int64
+	time       time.Time
+}
+
+func newDiscoveryThrottle() *discoveryThrottle {
+	return &discoveryThrottle{discoveries: map[types.NamespacedName]discovery{}}
+}
+
+// due returns whether the resources selected by the stack have to be counted
+// again at now.
+func (t *discoveryThrottle) due(ms *stack.MonitoringStack, now time.Time) bool {
+	if ms.Status.DiscoveredResources == nil {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d, ok := t.discoveries[client.ObjectKeyFromObject(ms)]
+	return !ok || d.generation != ms.Generation || now.Sub(d.time) >= resourceDiscoveryResyncInterval
+}
+
+// counted records that the resources selected by the stack were counted at
+// now.
+func (t *discoveryThrottle) counted(ms *stack.MonitoringStack, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.discoveries[client.ObjectKeyFromObject(ms)] = discovery{generation: ms.Generation, time: now}
+}
+
+// forget removes the stack, e.g. when it is deleted.
+func (t *discoveryThrottle) forget(key types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.discoveries, key)
+}
+
+// discoveredResources counts the resources matching the selectors of the
+// Prometheus and the Alertmanager of the stack. Only the metadata of the
+// resources is read from the API server to avoid caching all of them.
+func (rm resourceManager) discoveredResources(ctx context.Context, ms *stack.MonitoringStack) (*stack.DiscoveredResources, error) {
+	namespaces, err := rm.selectedNamespaces(ctx, ms)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prometheus selects no resources when its selectors are nil while
+	// Alertmanager selects all of them
+	alertmanagerSelector := ms.Spec.ResourceSelector
+	if alertmanagerSelector == nil {
+		alertmanagerSelector = &metav1.LabelSelector{}
+	}
+
+	type resourceCount struct {
+		gvk      schema.GroupVersionKind
+		selector *metav1.LabelSelector
+		count    *int32
+	}
+	discovered := &stack.DiscoveredResources{}
+	counts := []resourceCount{
+		{monv1.SchemeGroupVersion.WithKind(monv1.ServiceMonitorsKind), ms.Spec.ResourceSelector, &discovered.ServiceMonitors},
+		{monv1.SchemeGroupVersion.WithKind(monv1.PodMonitorsKind), ms.Spec.ResourceSelector, &discovered.PodMonitors},
+		{monv1.SchemeGroupVersion.WithKind(monv1.ProbesKind), ms.Spec.ResourceSelector, &discovered.Probes},
+		{monv1.SchemeGroupVersion.WithKind(monv1.PrometheusRuleKind), ms.Spec.ResourceSelector, &discovered.PrometheusRules},
+		{monv1alpha1.SchemeGroupVersion.WithKind(monv1alpha1.ScrapeConfigsKind), ms.Spec.ResourceSelector, &discovered.ScrapeConfigs},
+	}
+	if !ms.Spec.AlertmanagerConfig.Disabled {
+		counts = append(counts, resourceCount{monv1alpha1.SchemeGroupVersion.WithKind(monv1alpha1.AlertmanagerConfigKind), alertmanagerSelector, &discovered.AlertmanagerConfigs})
+	}
+
+	for _, c := range counts {
+		if c.selector == nil {
+			continue
+		}
+		n, err := rm.countMatchingResources(ctx, c.gvk, c.selector, ms.Namespace, namespaces)
+		if err != nil {
+			return nil, err
+		}
+		*c.count = n
+	}
+	return discovered, nil
+}
+
+// selectedNamespaces returns the namespaces matching the namespace selector
+// of the stack, or nil when only the namespace of the stack is selected.
+func (rm resourceManager) selectedNamespaces(ctx context.Context, ms *stack.MonitoringStack) (map[string]struct{}, error) {
+	if ms.Spec.NamespaceSelector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ms.Spec.NamespaceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespace selector: %w", err)
+	}
+	list := &metav1.PartialObjectMetadataList{}
+	list.SetGroupVersionKind(v1.SchemeGroupVersion.WithKind("NamespaceList"))
+	if err := rm.apiReader.List(ctx, list, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	namespaces := make(map[string]struct{}, len(list.Items))
+	for _, ns := range list.Items {
+		namespaces[ns.Name] = struct{}{}
+	}
+	return namespaces, nil
+}
+
+// countMatchingResources counts the resources of the given kind matching
+// selector in the selected namespaces, or in namespace when namespaces is
+// nil. Kinds which aren't served by the cluster have no resources.
+func (rm resourceManager) countMatchingResources(ctx context.Context, gvk schema.GroupVersionKind, labelSelector *metav1.LabelSelector, namespace string, namespaces map[string]struct{}) (int32, error) {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return 0, fmt.Errorf("invalid resource selector: %w", err)
+	}
+	opts := []client.ListOption{client.MatchingLabelsSelector{Selector: selector}}
+	if namespaces == nil {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+
+	list := &metav1.PartialObjectMetadataList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	err = rm.apiReader.List(ctx, list, opts...)
+	if meta.IsNoMatchError(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to list %s: %w", gvk.Kind, err)
+	}
+
+	var n int32
+	for _, item := range list.Items {
+		if namespaces != nil {
+			if _, ok := namespaces[item.Namespace]; !ok {
+				continue
+			}
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,108 @@
+package monitoringstack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	monv1alpha1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDiscoveredResources(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NilError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NilError(t, monv1.AddToScheme(scheme))
+	assert.NilError(t, monv1alpha1.AddToScheme(scheme))
+
+	selected := map[string]string{"team": "a"}
+	objectMeta := func(name string, namespace string, labels map[string]string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: objectMeta("ns", "", selected)},
+		&corev1.Namespace{ObjectMeta: objectMeta("other", "", selected)},
+		&corev1.Namespace{ObjectMeta: objectMeta("ignored", "", nil)},
+		&monv1.ServiceMonitor{ObjectMeta: objectMeta("sm", "ns", selected)},
+		&monv1.ServiceMonitor{ObjectMeta: objectMeta("unlabelled", "ns", nil)},
+		&monv1.ServiceMonitor{ObjectMeta: objectMeta("sm", "other", selected)},
+		&monv1.ServiceMonitor{ObjectMeta: objectMeta("sm", "ignored", selected)},
+		&monv1.PodMonitor{ObjectMeta: objectMeta("pm", "ns", selected)},
+		&monv1.PrometheusRule{ObjectMeta: objectMeta("rule", "other", selected)},
+		&monv1alpha1.AlertmanagerConfig{ObjectMeta: objectMeta("amc", "ns", nil)},
+	).Build()
+	rm := resourceManager{apiReader: c}
+
+	ms := &stack.MonitoringStack{
+		ObjectMeta: objectMeta("stack", "ns", nil),
+		Spec: stack.MonitoringStackSpec{
+			ResourceSelector: &metav1.LabelSelector{MatchLabels: selected},
+		},
+	}
+
+	// only the namespace of the stack is selected by default
+	discovered, err := rm.discoveredResources(context.Background(), ms)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, discovered, &stack.DiscoveredResources{
+		ServiceMonitors: 1,
+		PodMonitors:     1,
+	})
+
+	ms.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: selected}
+	discovered, err = rm.discoveredResources(context.Background(), ms)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, discovered, &stack.DiscoveredResources{
+		ServiceMonitors: 2,
+		PodMonitors:     1,
+		PrometheusRules: 1,
+	})
+
+	// Prometheus selects no resources without a resource selector while
+	// Alertmanager selects all of them
+	ms.Spec.ResourceSelector = nil
+	discovered, err = rm.discoveredResources(context.Background(), ms)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, discovered, &stack.DiscoveredResources{
+		AlertmanagerConfigs: 1,
+	})
+
+	ms.Spec.AlertmanagerConfig.Disabled = true
+	discovered, err = rm.discoveredResources(context.Background(), ms)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, discovered, &stack.DiscoveredResources{})
+}
+
+func TestDiscoveryThrottle(t *testing.T) {
+	ms := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "ns", Generation: 1},
+	}
+	throttle := newDiscoveryThrottle()
+	now := time.Now()
+
+	// resources are counted until counts are reported
+	assert.Assert(t, throttle.due(ms, now))
+	throttle.counted(ms, now)
+	assert.Assert(t, throttle.due(ms, now))
+
+	// and then once per resync interval
+	ms.Status.DiscoveredResources = &stack.DiscoveredResources{}
+	assert.Assert(t, !throttle.due(ms, now.Add(resourceDiscoveryResyncInterval-time.Second)))
+	assert.Assert(t, throttle.due(ms, now.Add(resourceDiscoveryResyncInterval)))
+
+	// or when the spec of the stack changes
+	ms.Generation = 2
+	assert.Assert(t, throttle.due(ms, now))
+
+	throttle.counted(ms, now)
+	throttle.forget(client.ObjectKeyFromObject(ms))
+	assert.Assert(t, throttle.due(ms, now))
+}
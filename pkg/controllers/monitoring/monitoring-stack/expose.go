@@ -0,0 +1,200 @@
+package monitoringstack
+
+import (
+	"strings"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certs"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// routeGVK is the kind of OpenShift Routes. Routes are handled as
+// unstructured objects since the API is only available on OpenShift.
+var routeGVK = schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"}
+
+// exposeReconcilers returns the reconcilers of the Ingresses or, when
+// routesSupported is true, the Routes exposing the web endpoints of the stack.
+// ca is the CA of the certificate provisioned for the stack, if any.
+func exposeReconcilers(ms *stack.MonitoringStack, instanceSelectorKey string, instanceSelectorValue string, routesSupported bool, ca *certs.CA) []reconciler.Reconciler {
+	deployAlertmanager := !ms.Spec.AlertmanagerConfig.Disabled
+	prometheusEndpoint := exposedPrometheus(ms)
+	alertmanagerEndpoint := exposedAlertmanager(ms)
+
+	components := []struct {
+		name     string
+		endpoint *stack.ExposedEndpoint
+		exposed  bool
+	}{
+		{ms.Name + "-prometheus", prometheusEndpoint, prometheusEndpoint != nil},
+		{ms.Name + "-alertmanager", alertmanagerEndpoint, deployAlertmanager && alertmanagerEndpoint != nil},
+	}
+
+	var reconcilers []reconciler.Reconciler
+	for _, c := range components {
+		endpoint := stack.ExposedEndpoint{}
+		if c.endpoint != nil {
+			endpoint = *c.endpoint
+		}
+		reconcilers = append(reconcilers, reconciler.NewOptionalUpdater(
+			newIngress(ms, c.name, endpoint, instanceSelectorKey, instanceSelectorValue), ms, c.exposed && !routesSupported))
+		// The Route API doesn't exist outside of OpenShift, so there is nothing to delete.
+		if routesSupported {
+			reconcilers = append(reconcilers, reconciler.NewOptionalUpdater(
+				newRoute(ms, c.name, endpoint, instanceSelectorKey, instanceSelectorValue, ca), ms, c.exposed))
+		}
+	}
+	return reconcilers
+}
+
+func exposedPrometheus(ms *stack.MonitoringStack) *stack.ExposedEndpoint {
+	if ms.Spec.Web == nil || ms.Spec.Web.Expose == nil {
+		return nil
+	}
+	return ms.Spec.Web.Expose.Prometheus
+}
+
+func exposedAlertmanager(ms *stack.MonitoringStack) *stack.ExposedEndpoint {
+	if ms.Spec.Web == nil || ms.Spec.Web.Expose == nil {
+		return nil
+	}
+	return ms.Spec.Web.Expose.Alertmanager
+}
+
+// routePrefix returns the path under which an exposed component serves its
+// web endpoint. It is empty when the component isn't exposed or is served
+// from the root path.
+func routePrefix(endpoint *stack.ExposedEndpoint) string {
+	if endpoint == nil {
+		return ""
+	}
+	return strings.TrimRight(endpoint.Path, "/")
+}
+
+// externalURL returns the URL under which an exposed component is reachable
+// from outside of the cluster. It is empty when the host isn't known.
+func externalURL(endpoint *stack.ExposedEndpoint) string {
+	if endpoint == nil || endpoint.Host == "" {
+		return ""
+	}
+	scheme := "http"
+	if endpoint.TLSSecretName != "" {
+		scheme = "https"
+	}
+	return scheme + "://" + endpoint.Host + routePrefix(endpoint)
+}
+
+func newIngress(ms *stack.MonitoringStack, name string, endpoint stack.ExposedEndpoint, instanceSelectorKey string, instanceSelectorValue string) *networkingv1.Ingress {
+	path := routePrefix(&endpoint)
+	if path == "" {
+		path = "/"
+	}
+	pathType := networkingv1.PathTypePrefix
+
+	ingress := &networkingv1.Ingress{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: networkingv1.SchemeGroupVersion.String(),
+			Kind:       "Ingress",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ms.Namespace,
+			Labels:    objectLabels(name, ms.Name, instanceSelectorKey, instanceSelectorValue),
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: endpoint.IngressClassName,
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: endpoint.Host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     path,
+									PathType: &pathType,
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: name,
+											Port: networkingv1.ServiceBackendPort{Name: "web"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// The annotation is understood by the NGINX ingress controller. Other
+	// controllers need to be configured to connect to the backend over TLS.
+	if tlsSecretName(ms) != "" {
+		ingress.Annotations = map[string]string{
+			"nginx.ingress.kubernetes.io/backend-protocol": "HTTPS",
+		}
+	}
+
+	if endpoint.TLSSecretName != "" {
+		tls := networkingv1.IngressTLS{SecretName: endpoint.TLSSecretName}
+		if endpoint.Host != "" {
+			tls.Hosts = []string{endpoint.Host}
+		}
+		ingress.Spec.TLS = []networkingv1.IngressTLS{tls}
+	}
+	return ingress
+}
+
+func newRoute(ms *stack.MonitoringStack, name string, endpoint stack.ExposedEndpoint, instanceSelectorKey string, instanceSelectorValue string, ca *certs.CA) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"to": map[string]interface{}{
+			"kind": "Service",
+			"name": name,
+		},
+		"port": map[string]interface{}{
+			"targetPort": "web",
+		},
+	}
+	if endpoint.Host != "" {
+		spec["host"] = endpoint.Host
+	}
+	if path := routePrefix(&endpoint); path != "" {
+		spec["path"] = path
+	}
+	tls := map[string]interface{}{
+		"termination":                   "edge",
+		"insecureEdgeTerminationPolicy": "Redirect",
+	}
+	if endpoint.TLSSecretName != "" {
+		tls["externalCertificate"] = map[string]interface{}{
+			"name": endpoint.TLSSecretName,
+		}
+	}
+	// the router connects to components serving TLS with a new TLS connection
+	if tlsSecretName(ms) != "" {
+		tls["termination"] = "reencrypt"
+		if provisionsCertificate(ms) && ca != nil {
+			tls["destinationCACertificate"] = string(ca.CertPEM())
+		}
+	}
+	if endpoint.TLSSecretName != "" || tlsSecretName(ms) != "" {
+		spec["tls"] = tls
+	}
+
+	route := newRouteObject()
+	route.SetName(name)
+	route.SetNamespace(ms.Namespace)
+	route.SetLabels(objectLabels(name, ms.Name, instanceSelectorKey, instanceSelectorValue))
+	route.Object["spec"] = spec
+	return route
+}
+
+func newRouteObject() *unstructured.Unstructured {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(routeGVK)
+	return route
+}
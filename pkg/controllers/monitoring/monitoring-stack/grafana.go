@@ -0,0 +1,30 @@
+package monitoringstack
+
+import (
+	"fmt"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certs"
+	"github.com/rhobs/observability-operator/pkg/grafana"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// newGrafanaDataSource returns the Grafana datasource of the Prometheus of
+// the stack. ca is the CA of the certificate provisioned for the stack, if
+// any.
+func newGrafanaDataSource(ms *stack.MonitoringStack, instanceSelectorKey string, instanceSelectorValue string, ca *certs.CA) *unstructured.Unstructured {
+	name := ms.Name + "-prometheus"
+	scheme := "http"
+	if tlsSecretName(ms) != "" {
+		scheme = "https"
+	}
+	var caCert []byte
+	if provisionsCertificate(ms) && ca != nil {
+		caCert = ca.CertPEM()
+	}
+	// Prometheus serves its API under the route prefix of its exposed endpoint
+	url := fmt.Sprintf("%s://%s.%s.svc:9090%s", scheme, name, ms.Namespace, routePrefix(exposedPrometheus(ms)))
+	return grafana.NewPrometheusDataSource(name, ms.Namespace,
+		objectLabels(name, ms.Name, instanceSelectorKey, instanceSelectorValue), url, caCert)
+}
@@ -0,0 +1,144 @@
+package monitoringstack
+
+import (
+	"context"
+	"fmt"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// removeLegacyResources removes the cluster roles and cluster role bindings
+// created for the stacks before their names were qualified with the namespace
+// of the stack, along with the role bindings of the stacks referencing them.
+// It runs once when the operator starts, since the operator doesn't create
+// legacy resources anymore. Failures are logged and don't stop the operator.
+func (rm resourceManager) removeLegacyResources(ctx context.Context) error {
+	stacks := &stack.MonitoringStackList{}
+	if err := rm.k8sClient.List(ctx, stacks); err != nil {
+		rm.logger.Error(err, "failed to list monitoring stacks, legacy resources are not removed")
+		return nil
+	}
+
+	// legacy names only depend on the name of the stack, so they are shared
+	// by the stacks with the same name in different namespaces
+	byName := map[string][]*stack.MonitoringStack{}
+	for i := range stacks.Items {
+		ms := &stacks.Items[i]
+		byName[ms.Name] = append(byName[ms.Name], ms)
+	}
+	for name, named := range byName {
+		for _, component := range []string{"prometheus", "alertmanager"} {
+			if err := rm.removeLegacyComponentResources(ctx, name+"-"+component, named); err != nil {
+				rm.logger.Error(err, "failed to remove legacy resources", "name", name+"-"+component)
+			}
+		}
+	}
+	return nil
+}
+
+// removeLegacyComponentResources removes the legacy resources with the given
+// name of the stacks. Legacy resources carry no labels, hence a cluster-scoped
+// resource is only removed when it was applied by the operator and isn't used
+// by subjects outside the namespaces of the stacks. Only the bindings with the
+// legacy name are looked up.
+func (rm resourceManager) removeLegacyComponentResources(ctx context.Context, name string, stacks []*stack.MonitoringStack) error {
+	namespaces := map[string]bool{}
+	bound := false
+
+	for _, ms := range stacks {
+		namespaces[ms.Namespace] = true
+
+		// the role binding keeps its name but its role reference is immutable
+		rb := &rbacv1.RoleBinding{}
+		rb.SetGroupVersionKind(rbacv1.SchemeGroupVersion.WithKind("RoleBinding"))
+		found, err := getLegacyResource(ctx, rm.k8sClient, client.ObjectKey{Name: name, Namespace: ms.Namespace}, rb)
+		if err != nil {
+			return err
+		}
+		if !found || !referencesClusterRole(rb.RoleRef, name) {
+			continue
+		}
+		if !metav1.IsControlledBy(rb, ms) {
+			bound = true
+			continue
+		}
+		if err := rm.events.Reconcile(ctx, rm.k8sClient, rm.scheme, ms, reconciler.NewDeleter(rb)); err != nil {
+			return err
+		}
+	}
+
+	// events about the shared resources are recorded on one of the stacks
+	owner := stacks[0]
+
+	crb := &rbacv1.ClusterRoleBinding{}
+	crb.SetGroupVersionKind(rbacv1.SchemeGroupVersion.WithKind("ClusterRoleBinding"))
+	found, err := getLegacyResource(ctx, rm.apiReader, client.ObjectKey{Name: name}, crb)
+	if err != nil {
+		return err
+	}
+	if found && appliedByOperator(crb) && subjectsInNamespaces(crb.Subjects, namespaces) {
+		if err := rm.events.Reconcile(ctx, rm.k8sClient, rm.scheme, owner, reconciler.NewDeleter(crb)); err != nil {
+			return err
+		}
+	} else if found && referencesClusterRole(crb.RoleRef, name) {
+		bound = true
+	}
+
+	cr := &rbacv1.ClusterRole{}
+	cr.SetGroupVersionKind(rbacv1.SchemeGroupVersion.WithKind("ClusterRole"))
+	found, err = getLegacyResource(ctx, rm.apiReader, client.ObjectKey{Name: name}, cr)
+	if err != nil {
+		return err
+	}
+	if !found || !appliedByOperator(cr) || bound {
+		return nil
+	}
+	return rm.events.Reconcile(ctx, rm.k8sClient, rm.scheme, owner, reconciler.NewDeleter(cr))
+}
+
+// getLegacyResource reads the resource with reader and returns whether it
+// exists. Cluster-scoped legacy resources aren't cached, so they are read with
+// the API reader.
+func getLegacyResource(ctx context.Context, reader client.Reader, key client.ObjectKey, obj client.Object) (bool, error) {
+	err := reader.Get(ctx, key, obj)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get legacy %s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, key.Name, err)
+	}
+	return true, nil
+}
+
+func appliedByOperator(obj client.Object) bool {
+	for _, f := range obj.GetManagedFields() {
+		if f.Manager == reconciler.FieldManager && f.Operation == metav1.ManagedFieldsOperationApply {
+			return true
+		}
+	}
+	return false
+}
+
+// subjectsInNamespaces returns whether all subjects are service accounts of
+// the namespaces.
+func subjectsInNamespaces(subjects []rbacv1.Subject, namespaces map[string]bool) bool {
+	if len(subjects) == 0 {
+		return false
+	}
+	for _, s := range subjects {
+		if s.Kind != rbacv1.ServiceAccountKind || !namespaces[s.Namespace] {
+			return false
+		}
+	}
+	return true
+}
+
+func referencesClusterRole(ref rbacv1.RoleRef, name string) bool {
+	return ref.Kind == "ClusterRole" && ref.Name == name
+}
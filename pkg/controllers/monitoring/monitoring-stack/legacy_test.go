@@ -0,0 +1,104 @@
+package monitoringstack
+
+import (
+	"context"
+	"testing"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+
+	"github.com/go-logr/logr"
+	"gotest.tools/v3/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRemoveLegacyResources(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NilError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NilError(t, stack.AddToScheme(scheme))
+
+	ms := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "a", UID: "stack-uid"},
+	}
+	applied := []metav1.ManagedFieldsEntry{{
+		Manager:   reconciler.FieldManager,
+		Operation: metav1.ManagedFieldsOperationApply,
+	}}
+	serviceAccount := func(name string, namespace string) []rbacv1.Subject {
+		return []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: name, Namespace: namespace}}
+	}
+	clusterRoleRef := func(name string) rbacv1.RoleRef {
+		return rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: name}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		ms,
+		&stack.MonitoringStack{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "a"}},
+		// the legacy Prometheus resources of the stack are removed
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "foo-prometheus", ManagedFields: applied}},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-prometheus", ManagedFields: applied},
+			Subjects:   serviceAccount("foo-prometheus", "a"),
+			RoleRef:    clusterRoleRef("foo-prometheus"),
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo-alertmanager",
+				Namespace: "a",
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: stack.GroupVersion.String(),
+					Kind:       "MonitoringStack",
+					Name:       "foo",
+					UID:        "stack-uid",
+					Controller: pointer.Bool(true),
+				}},
+			},
+			Subjects: serviceAccount("foo-alertmanager", "a"),
+			RoleRef:  clusterRoleRef("foo-alertmanager"),
+		},
+		// the same-named cluster role binding grants permissions in a
+		// namespace without a stack named foo, so it and its role are kept
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "foo-alertmanager", ManagedFields: applied}},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-alertmanager", ManagedFields: applied},
+			Subjects:   serviceAccount("foo-alertmanager", "b"),
+			RoleRef:    clusterRoleRef("foo-alertmanager"),
+		},
+		// resources not applied by the operator are never removed
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "bar-prometheus"}},
+	).Build()
+	rm := resourceManager{
+		k8sClient: c,
+		apiReader: c,
+		scheme:    scheme,
+		logger:    logr.Discard(),
+		events:    reconciler.NewEventRecorder(record.NewFakeRecorder(10)),
+	}
+
+	ctx := context.Background()
+	assert.NilError(t, rm.removeLegacyResources(ctx))
+
+	for _, obj := range []client.Object{
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "foo-prometheus"}},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "foo-prometheus"}},
+		&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "foo-alertmanager", Namespace: "a"}},
+	} {
+		err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		assert.Assert(t, apierrors.IsNotFound(err), "%s should be removed", obj.GetName())
+	}
+	for _, obj := range []client.Object{
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "foo-alertmanager"}},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "foo-alertmanager"}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "bar-prometheus"}},
+	} {
+		assert.NilError(t, c.Get(ctx, client.ObjectKeyFromObject(obj), obj))
+	}
+}
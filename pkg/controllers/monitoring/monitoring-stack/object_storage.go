@@ -0,0 +1,237 @@
+package monitoringstack
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	"github.com/go-logr/logr"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+const (
+	// thanosSidecarHTTPPort is the port on which the Thanos sidecar serves
+	// its metrics.
+	thanosSidecarHTTPPort = 10902
+
+	// objectStorageResyncInterval is the interval at which the sidecars of
+	// the stacks with object storage are scraped.
+	objectStorageResyncInterval = 5 * time.Minute
+
+	shipperUploadsMetric        = "thanos_shipper_uploads_total"
+	shipperUploadFailuresMetric = "thanos_shipper_upload_failures_total"
+	lastSuccessfulUploadMetric  = "thanos_objstore_bucket_last_successful_upload_time"
+)
+
+var sidecarMetricsClient = &http.Client{Timeout: 5 * time.Second}
+
+// objectStorageConfig returns the reference to the object storage config of
+// the Thanos sidecar or nil when the blocks aren't uploaded.
+func objectStorageConfig(ms *stack.MonitoringStack) *v1.SecretKeySelector {
+	if ms.Spec.ObjectStorage == nil {
+		return nil
+	}
+	return &ms.Spec.ObjectStorage.Secret
+}
+
+// prometheusPods lists the Prometheus pods of the stack. The pods are read
+// from the API server to avoid caching all pods of the cluster.
+func (rm resourceManager) prometheusPods(ctx context.Context, ms *stack.MonitoringStack) ([]v1.Pod, error) {
+	pods := &v1.PodList{}
+	err := rm.apiReader.List(ctx, pods,
+		client.InNamespace(ms.Namespace),
+		client.MatchingLabels(podLabels("prometheus", ms.Name)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Prometheus pods: %w", err)
+	}
+	return pods.Items, nil
+}
+
+// uploadScraper scrapes the Thanos sidecars of the stacks with object storage
+// in the background, so that reconciliations never wait for the sidecars. A
+// reconciliation of a stack is triggered whenever its uploads change.
+type uploadScraper struct {
+	client client.Reader
+	pods   func(context.Context, *stack.MonitoringStack) ([]v1.Pod, error)
+	logger logr.Logger
+	events chan event.GenericEvent
+
+	mu      sync.Mutex
+	uploads map[types.NamespacedName]scrapedUploads
+}
+
+// scrapedUploads holds the uploads of the sidecars of a stack or the error
+// preventing the scrape.
+type scrapedUploads struct {
+	status *stack.ObjectStorageStatus
+	err    error
+}
+
+func newUploadScraper(c client.Reader, pods func(context.Context, *stack.MonitoringStack) ([]v1.Pod, error), logger logr.Logger) *uploadScraper {
+	return &uploadScraper{
+		client:  c,
+		pods:    pods,
+		logger:  logger,
+		events:  make(chan event.GenericEvent),
+		uploads: map[types.NamespacedName]scrapedUploads{},
+	}
+}
+
+// Start scrapes the sidecars periodically until the context is done.
+func (s *uploadScraper) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, s.scrape, objectStorageResyncInterval)
+	return nil
+}
+
+// NeedLeaderElection makes the scraper run only on the leader, which
+// reconciles the stacks.
+func (s *uploadScraper) NeedLeaderElection() bool {
+	return true
+}
+
+// get returns the last uploads scraped for the stack and whether the stack
+// was scraped already.
+func (s *uploadScraper) get(key types.NamespacedName) (*stack.ObjectStorageStatus, error, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uploads, ok := s.uploads[key]
+	return uploads.status, uploads.err, ok
+}
+
+func (s *uploadScraper) scrape(ctx context.Context) {
+	stacks := &stack.MonitoringStackList{}
+	if err := s.client.List(ctx, stacks); err != nil {
+		s.logger.Error(err, "failed to list monitoring stacks, Thanos sidecars are not scraped")
+		return
+	}
+
+	scraped := make(map[types.NamespacedName]scrapedUploads, len(stacks.Items))
+	for i := range stacks.Items {
+		ms := &stacks.Items[i]
+		if ms.Spec.ObjectStorage == nil {
+			continue
+		}
+		var uploads scrapedUploads
+		pods, err := s.pods(ctx, ms)
+		if err == nil {
+			uploads.status, err = objectStorageStatus(ctx, pods)
+		}
+		uploads.err = err
+
+		key := client.ObjectKeyFromObject(ms)
+		scraped[key] = uploads
+		previous, previousErr, found := s.get(key)
+		s.set(key, uploads)
+		// errors are compared by message since they are recreated by every scrape
+		if found && equality.Semantic.DeepEqual(previous, uploads.status) && fmt.Sprint(previousErr) == fmt.Sprint(uploads.err) {
+			continue
+		}
+		select {
+		case s.events <- event.GenericEvent{Object: ms}:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	// forget the stacks which were deleted or don't use object storage anymore
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.uploads {
+		if _, ok := scraped[key]; !ok {
+			delete(s.uploads, key)
+		}
+	}
+}
+
+func (s *uploadScraper) set(key types.NamespacedName, uploads scrapedUploads) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uploads[key] = uploads
+}
+
+// objectStorageStatus scrapes the metrics of the ready Thanos sidecars of the
+// running Prometheus pods of the stack and sums up their uploads.
+func objectStorageStatus(ctx context.Context, pods []v1.Pod) (*stack.ObjectStorageStatus, error) {
+	status := &stack.ObjectStorageStatus{}
+	for _, pod := range pods {
+		if pod.Status.Phase != v1.PodRunning || pod.Status.PodIP == "" || !containerReady(pod, thanosSidecarContainer) {
+			continue
+		}
+		url := fmt.Sprintf("http://%s/metrics", net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(thanosSidecarHTTPPort)))
+		families, err := scrapeMetrics(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scrape the Thanos sidecar of pod %s: %w", pod.Name, err)
+		}
+		addSidecarUploads(status, families)
+	}
+	return status, nil
+}
+
+func scrapeMetrics(ctx context.Context, url string) (map[string]*dto.MetricFamily, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := sidecarMetricsClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	return parser.TextToMetricFamilies(resp.Body)
+}
+
+// addSidecarUploads adds the uploads reported by the metrics of a sidecar to
+// the status.
+func addSidecarUploads(status *stack.ObjectStorageStatus, families map[string]*dto.MetricFamily) {
+	status.Uploads += int64(sumMetric(families[shipperUploadsMetric]))
+	status.UploadFailures += int64(sumMetric(families[shipperUploadFailuresMetric]))
+
+	mf := families[lastSuccessfulUploadMetric]
+	if mf == nil {
+		return
+	}
+	for _, m := range mf.GetMetric() {
+		seconds := m.GetGauge().GetValue()
+		if seconds <= 0 {
+			continue
+		}
+		t := metav1.Unix(int64(seconds), 0)
+		if status.LastSuccessfulUploadTime == nil || status.LastSuccessfulUploadTime.Before(&t) {
+			status.LastSuccessfulUploadTime = &t
+		}
+	}
+}
+
+func sumMetric(mf *dto.MetricFamily) float64 {
+	var sum float64
+	for _, m := range mf.GetMetric() {
+		switch {
+		case m.Counter != nil:
+			sum += m.GetCounter().GetValue()
+		case m.Gauge != nil:
+			sum += m.GetGauge().GetValue()
+		case m.Untyped != nil:
+			sum += m.GetUntyped().GetValue()
+		}
+	}
+	return sum
+}
@@ -0,0 +1,114 @@
+package monitoringstack
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/common/expfmt"
+	"gotest.tools/v3/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const sidecarMetrics = `# HELP thanos_shipper_uploads_total Total number of uploaded blocks.
+# TYPE thanos_shipper_uploads_total counter
+thanos_shipper_uploads_total 3
+# HELP thanos_shipper_upload_failures_total Total number of block upload failures.
+# TYPE thanos_shipper_upload_failures_total counter
+thanos_shipper_upload_failures_total 1
+# HELP thanos_objstore_bucket_last_successful_upload_time Second timestamp of the last successful upload to the bucket.
+# TYPE thanos_objstore_bucket_last_successful_upload_time gauge
+thanos_objstore_bucket_last_successful_upload_time{bucket="thanos"} 1.6e+09
+`
+
+func TestAddSidecarUploads(t *testing.T) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(sidecarMetrics))
+	assert.NilError(t, err)
+
+	status := &stack.ObjectStorageStatus{}
+	addSidecarUploads(status, families)
+	addSidecarUploads(status, families)
+	assert.Equal(t, status.Uploads, int64(6))
+	assert.Equal(t, status.UploadFailures, int64(2))
+	assert.Equal(t, status.LastSuccessfulUploadTime.Unix(), int64(1600000000))
+}
+
+func TestObjectStorageConfig(t *testing.T) {
+	ms := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "ns"},
+		Spec: stack.MonitoringStackSpec{
+			PrometheusConfig: &stack.PrometheusConfig{},
+		},
+	}
+	prom := newPrometheus(ms, "stack", "", "key", "value", componentImages{})
+	assert.Assert(t, prom.Spec.Thanos.ObjectStorageConfig == nil)
+
+	ms.Spec.ObjectStorage = &stack.ObjectStorageConfig{
+		Secret: v1.SecretKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{Name: "thanos-objstore"},
+			Key:                  "objstore.yaml",
+		},
+	}
+	prom = newPrometheus(ms, "stack", "", "key", "value", componentImages{})
+	assert.DeepEqual(t, *prom.Spec.Thanos.ObjectStorageConfig, ms.Spec.ObjectStorage.Secret)
+}
+
+func TestUploadScraper(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NilError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NilError(t, stack.AddToScheme(scheme))
+
+	ms := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "ns"},
+		Spec: stack.MonitoringStackSpec{
+			ObjectStorage: &stack.ObjectStorageConfig{},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		ms,
+		&stack.MonitoringStack{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns"}},
+	).Build()
+
+	// the sidecar isn't ready, hence it isn't scraped
+	pods := func(context.Context, *stack.MonitoringStack) ([]v1.Pod, error) {
+		return []v1.Pod{{
+			ObjectMeta: metav1.ObjectMeta{Name: "prometheus-stack-0", Namespace: "ns"},
+			Status: v1.PodStatus{
+				Phase:             v1.PodRunning,
+				PodIP:             "10.0.0.1",
+				ContainerStatuses: []v1.ContainerStatus{{Name: thanosSidecarContainer}},
+			},
+		}}, nil
+	}
+	s := newUploadScraper(c, pods, logr.Discard())
+	ctx := context.Background()
+
+	// new uploads trigger a reconciliation of the stack
+	go s.scrape(ctx)
+	e := <-s.events
+	assert.Equal(t, e.Object.GetName(), "stack")
+	uploads, err, scraped := s.get(client.ObjectKeyFromObject(ms))
+	assert.Assert(t, scraped)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, uploads, &stack.ObjectStorageStatus{})
+	_, _, scraped = s.get(client.ObjectKey{Name: "other", Namespace: "ns"})
+	assert.Assert(t, !scraped)
+
+	// unchanged uploads don't
+	s.scrape(ctx)
+
+	ms.Spec.ObjectStorage = nil
+	assert.NilError(t, c.Update(ctx, ms))
+	s.scrape(ctx)
+	_, _, scraped = s.get(client.ObjectKeyFromObject(ms))
+	assert.Assert(t, !scraped)
+}
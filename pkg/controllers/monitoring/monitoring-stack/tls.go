@@ -0,0 +1,145 @@
+package monitoringstack
+
+import (
+	"path"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certs"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// secretsMountPath is the directory in which Prometheus Operator mounts the
+// secrets listed in the Prometheus spec.
+const secretsMountPath = "/etc/prometheus/secrets"
+
+// tlsSecretName returns the name of the secret holding the certificates of
+// the stack components. It is empty when TLS is disabled.
+func tlsSecretName(ms *stack.MonitoringStack) string {
+	if ms.Spec.TLS == nil {
+		return ""
+	}
+	if ms.Spec.TLS.CertificateSecretName != "" {
+		return ms.Spec.TLS.CertificateSecretName
+	}
+	return provisionedTLSSecretName(ms)
+}
+
+func provisionedTLSSecretName(ms *stack.MonitoringStack) string {
+	return ms.Name + "-tls"
+}
+
+// provisionsCertificate returns true when the operator provisions the
+// certificate of the stack.
+func provisionsCertificate(ms *stack.MonitoringStack) bool {
+	return ms.Spec.TLS != nil && ms.Spec.TLS.CertificateSecretName == ""
+}
+
+// tlsReconcilers returns the reconcilers of the certificate provisioned for
+// the stack. The certificate is removed when TLS is disabled or a secret is
+// referenced. ca is only used when the certificate is provisioned.
+func tlsReconcilers(ms *stack.MonitoringStack, instanceSelectorKey string, instanceSelectorValue string, ca *certs.CA) []reconciler.Reconciler {
+	secret := newTLSSecret(ms, instanceSelectorKey, instanceSelectorValue)
+	if provisionsCertificate(ms) {
+		return []reconciler.Reconciler{
+			certs.NewCertificateUpdater(secret, ms, ca, ms.Name, stackDNSNames(ms)),
+		}
+	}
+	// never delete a referenced secret that happens to have the same name
+	if tlsSecretName(ms) == secret.Name {
+		return nil
+	}
+	return []reconciler.Reconciler{reconciler.NewDeleter(secret)}
+}
+
+func newTLSSecret(ms *stack.MonitoringStack, instanceSelectorKey string, instanceSelectorValue string) *corev1.Secret {
+	name := provisionedTLSSecretName(ms)
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ms.Namespace,
+			Labels:    objectLabels(name, ms.Name, instanceSelectorKey, instanceSelectorValue),
+		},
+	}
+}
+
+// stackDNSNames returns the DNS names of the certificate provisioned for the
+// stack. It covers the services of all components as well as the common
+// server name of the Thanos sidecars.
+func stackDNSNames(ms *stack.MonitoringStack) []string {
+	names := []string{certs.ThanosSidecarServerName, "localhost"}
+	for _, svc := range []string{ms.Name + "-prometheus", ms.Name + "-alertmanager", ms.Name + "-thanos-sidecar"} {
+		names = append(names,
+			svc,
+			svc+"."+ms.Namespace,
+			svc+"."+ms.Namespace+".svc",
+			svc+"."+ms.Namespace+".svc.cluster.local",
+		)
+	}
+	return names
+}
+
+// serviceServerName returns the name used to verify the certificate of the
+// service of a component when connecting to the pods directly.
+func serviceServerName(ms *stack.MonitoringStack, component string) string {
+	return ms.Name + "-" + component + "." + ms.Namespace + ".svc"
+}
+
+// tlsFile returns the path of a key of the TLS secret in the Prometheus pods.
+func tlsFile(secretName string, key string) string {
+	return path.Join(secretsMountPath, secretName, key)
+}
+
+// webTLSConfig returns the web TLS config of Prometheus and Alertmanager
+// serving the certificate stored in secretName.
+func webTLSConfig(secretName string) *monv1.WebTLSConfig {
+	return &monv1.WebTLSConfig{
+		KeySecret: corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+			Key:                  certs.PrivateKeyKey,
+		},
+		Cert: monv1.SecretOrConfigMap{
+			Secret: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  certs.CertificateKey,
+			},
+		},
+	}
+}
+
+// fileTLSConfig returns a TLS config referencing the files of the TLS secret
+// mounted in the Prometheus pods.
+func fileTLSConfig(secretName string, serverName string) *monv1.TLSConfig {
+	return &monv1.TLSConfig{
+		SafeTLSConfig: monv1.SafeTLSConfig{
+			ServerName: serverName,
+		},
+		CAFile:   tlsFile(secretName, certs.CAKey),
+		CertFile: tlsFile(secretName, certs.CertificateKey),
+		KeyFile:  tlsFile(secretName, certs.PrivateKeyKey),
+	}
+}
+
+// scrapeTLSConfig returns the scheme and TLS settings of the self-scrape
+// jobs of a component.
+func scrapeTLSConfig(ms *stack.MonitoringStack, component string) string {
+	secretName := tlsSecretName(ms)
+	if secretName == "" {
+		return `
+  scheme: http`
+	}
+	return `
+  scheme: https
+  tls_config:
+    ca_file: ` + tlsFile(secretName, certs.CAKey) + `
+    cert_file: ` + tlsFile(secretName, certs.CertificateKey) + `
+    key_file: ` + tlsFile(secretName, certs.PrivateKeyKey) + `
+    server_name: ` + serviceServerName(ms, component)
+}
@@ -2,7 +2,10 @@ package thanos_querier
 
 import (
 	"fmt"
+	"path"
+	"reflect"
 
+	"github.com/rhobs/observability-operator/pkg/certs"
 	"github.com/rhobs/observability-operator/pkg/reconciler"
 
 	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
@@ -10,34 +13,101 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-func thanosComponentReconcilers(thanos *msoapi.ThanosQuerier, sidecarUrls []string) []reconciler.Reconciler {
+// tlsMountPath is the directory in which the client certificate of the
+// querier is mounted.
+const tlsMountPath = "/etc/thanos/tls"
+
+func thanosComponentReconcilers(thanos *msoapi.ThanosQuerier, sidecarUrls []string, thanosImage string, ca *certs.CA) []reconciler.Reconciler {
 	name := "thanos-querier-" + thanos.Name
-	return []reconciler.Reconciler{
+	reconcilers := []reconciler.Reconciler{
 		reconciler.NewUpdater(newServiceAccount(name, thanos.Namespace), thanos),
-		reconciler.NewUpdater(newThanosQuerierDeployment(name, thanos, sidecarUrls), thanos),
-		reconciler.NewUpdater(newService(name, thanos.Namespace), thanos),
+		reconciler.NewUpdater(newThanosQuerierDeployment(name, thanos, sidecarUrls, thanosImage), thanos),
+		reconciler.NewUpdater(newService(name, thanos), thanos),
 		reconciler.NewUpdater(newServiceMonitor(name, thanos.Namespace), thanos),
+		reconciler.NewOptionalUpdater(newPodDisruptionBudget(name, thanos.Namespace), thanos,
+			querierReplicas(thanos) > 1),
+	}
+
+	secret := newTLSSecret(name, thanos.Namespace)
+	switch {
+	case provisionsCertificate(thanos):
+		reconcilers = append(reconcilers, certs.NewCertificateUpdater(secret, thanos, ca, name, []string{name}))
+	case tlsSecretName(name, thanos) != secret.Name:
+		// never delete a referenced secret that happens to have the same name
+		reconcilers = append(reconcilers, reconciler.NewDeleter(secret))
+	}
+	return reconcilers
+}
+
+// tlsSecretName returns the name of the secret holding the client
+// certificate of the querier. It is empty when TLS is disabled.
+func tlsSecretName(name string, querier *msoapi.ThanosQuerier) string {
+	if querier.Spec.TLS == nil {
+		return ""
+	}
+	if querier.Spec.TLS.CertificateSecretName != "" {
+		return querier.Spec.TLS.CertificateSecretName
+	}
+	return name + "-tls"
+}
+
+// provisionsCertificate returns true when the operator provisions the client
+// certificate of the querier.
+func provisionsCertificate(querier *msoapi.ThanosQuerier) bool {
+	return querier.Spec.TLS != nil && querier.Spec.TLS.CertificateSecretName == ""
+}
+
+func newTLSSecret(name string, namespace string) *corev1.Secret {
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + "-tls",
+			Namespace: namespace,
+			Labels:    componentLabels(name),
+		},
 	}
 }
 
-func newThanosQuerierDeployment(name string, spec *msoapi.ThanosQuerier, sidecarUrls []string) *appsv1.Deployment {
+func newThanosQuerierDeployment(name string, spec *msoapi.ThanosQuerier, sidecarUrls []string, thanosImage string) *appsv1.Deployment {
 	args := []string{
 		"query",
-		"--grpc-address=127.0.0.1:10901",
-		"--http-address=127.0.0.1:9090",
+		fmt.Sprintf("--grpc-address=0.0.0.0:%d", grpcPort(spec)),
+		fmt.Sprintf("--http-address=0.0.0.0:%d", httpPort(spec)),
 		"--log.format=logfmt",
-		"--query.replica-label=prometheus_replica",
-		"--query.auto-downsampling",
+		fmt.Sprintf("--log.level=%s", querierLogLevel(spec)),
 	}
 	for _, endpoint := range sidecarUrls {
 		args = append(args, fmt.Sprintf("--endpoint=%s", endpoint))
 	}
+	args = append(args, queryArgs(spec)...)
 
-	for _, rl := range spec.Spec.ReplicaLabels {
-		args = append(args, fmt.Sprintf("--query.replica-label=%s", rl))
+	tlsSecret := tlsSecretName(name, spec)
+	if tlsSecret != "" {
+		serverName := spec.Spec.TLS.ServerName
+		if serverName == "" {
+			serverName = certs.ThanosSidecarServerName
+		}
+		args = append(args,
+			"--grpc-client-tls-secure",
+			fmt.Sprintf("--grpc-client-tls-cert=%s", path.Join(tlsMountPath, certs.CertificateKey)),
+			fmt.Sprintf("--grpc-client-tls-key=%s", path.Join(tlsMountPath, certs.PrivateKeyKey)),
+			fmt.Sprintf("--grpc-client-tls-ca=%s", path.Join(tlsMountPath, certs.CAKey)),
+			fmt.Sprintf("--grpc-client-server-name=%s", serverName),
+		)
+	}
+
+	// the image of the querier overrides the image configured for the operator
+	if spec.Spec.Image != "" {
+		thanosImage = spec.Spec.Image
 	}
 
 	thanos := &appsv1.Deployment{
@@ -51,7 +121,7 @@ func newThanosQuerierDeployment(name string, spec *msoapi.ThanosQuerier, sidecar
 			Labels:    componentLabels(name),
 		},
 		Spec: appsv1.DeploymentSpec{
-			Replicas: func(i int32) *int32 { return &i }(1),
+			Replicas: func(i int32) *int32 { return &i }(querierReplicas(spec)),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					"app.kubernetes.io/instance": name,
@@ -66,15 +136,40 @@ func newThanosQuerierDeployment(name string, spec *msoapi.ThanosQuerier, sidecar
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
 						{
-							Name:  "thanos-querier",
-							Args:  args,
-							Image: "quay.io/thanos/thanos:v0.24.0",
+							Name:      "thanos-querier",
+							Args:      args,
+							Image:     thanosImage,
+							Resources: defaultResources(spec.Spec.Resources),
 							Ports: []corev1.ContainerPort{
 								{
-									ContainerPort: 9090,
-									Name:          "metrics",
+									ContainerPort: httpPort(spec),
+									Name:          "http",
+								},
+								{
+									ContainerPort: grpcPort(spec),
+									Name:          "grpc",
 								},
 							},
+							LivenessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "/-/healthy",
+										Port: intstr.FromString("http"),
+									},
+								},
+								PeriodSeconds:    30,
+								FailureThreshold: 4,
+							},
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "/-/ready",
+										Port: intstr.FromString("http"),
+									},
+								},
+								PeriodSeconds:    5,
+								FailureThreshold: 20,
+							},
 							TerminationMessagePolicy: "FallbackToLogsOnError",
 						},
 					},
@@ -87,9 +182,203 @@ func newThanosQuerierDeployment(name string, spec *msoapi.ThanosQuerier, sidecar
 		},
 	}
 
+	podSpec := &thanos.Spec.Template.Spec
+	applyScheduling(podSpec, name, querierReplicas(spec), spec.Spec.Scheduling)
+
+	if tlsSecret != "" {
+		podSpec.Volumes = []corev1.Volume{{
+			Name: "tls",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: tlsSecret},
+			},
+		}}
+		podSpec.Containers[0].VolumeMounts = []corev1.VolumeMount{{
+			Name:      "tls",
+			MountPath: tlsMountPath,
+			ReadOnly:  true,
+		}}
+	}
+
 	return thanos
 }
 
+// queryArgs returns the flags of the query settings of the querier. Flags
+// which are not set in the spec are left to the defaults of Thanos.
+func queryArgs(querier *msoapi.ThanosQuerier) []string {
+	query := querier.Spec.Query
+	if query == nil {
+		query = &msoapi.ThanosQueryConfig{}
+	}
+
+	var args []string
+	if !query.DisableDeduplication {
+		args = append(args, "--query.replica-label=prometheus_replica")
+		for _, rl := range querier.Spec.ReplicaLabels {
+			args = append(args, fmt.Sprintf("--query.replica-label=%s", rl))
+		}
+	}
+	if !query.DisableAutoDownsampling {
+		args = append(args, "--query.auto-downsampling")
+	}
+	if query.Timeout != nil {
+		args = append(args, fmt.Sprintf("--query.timeout=%s", *query.Timeout))
+	}
+	if query.MaxConcurrent != nil {
+		args = append(args, fmt.Sprintf("--query.max-concurrent=%d", *query.MaxConcurrent))
+	}
+	if query.LookbackDelta != nil {
+		args = append(args, fmt.Sprintf("--query.lookback-delta=%s", *query.LookbackDelta))
+	}
+	if query.DefaultEvaluationInterval != nil {
+		args = append(args, fmt.Sprintf("--query.default-evaluation-interval=%s", *query.DefaultEvaluationInterval))
+	}
+	switch query.PartialResponseStrategy {
+	case msoapi.WarnPartialResponse:
+		args = append(args, "--query.partial-response")
+	case msoapi.AbortPartialResponse:
+		args = append(args, "--no-query.partial-response")
+	}
+	return args
+}
+
+// querierReplicas returns the number of replicas of the querier, which
+// defaults to 1.
+func querierReplicas(querier *msoapi.ThanosQuerier) int32 {
+	if querier.Spec.Replicas == nil {
+		return 1
+	}
+	return *querier.Spec.Replicas
+}
+
+func httpPort(querier *msoapi.ThanosQuerier) int32 {
+	if querier.Spec.HTTPPort == nil {
+		return 9090
+	}
+	return *querier.Spec.HTTPPort
+}
+
+func grpcPort(querier *msoapi.ThanosQuerier) int32 {
+	if querier.Spec.GRPCPort == nil {
+		return 10901
+	}
+	return *querier.Spec.GRPCPort
+}
+
+func querierLogLevel(querier *msoapi.ThanosQuerier) msoapi.LogLevel {
+	if querier.Spec.LogLevel == "" {
+		return msoapi.Info
+	}
+	return querier.Spec.LogLevel
+}
+
+// defaultResources returns the given resources or default requests when
+// they are empty.
+func defaultResources(resources corev1.ResourceRequirements) corev1.ResourceRequirements {
+	if !reflect.DeepEqual(resources, corev1.ResourceRequirements{}) {
+		return resources
+	}
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("10m"),
+			corev1.ResourceMemory: resource.MustParse("64Mi"),
+		},
+	}
+}
+
+// applyScheduling applies the scheduling config of the querier to the pods
+// of a component and spreads the replicas across nodes.
+func applyScheduling(podSpec *corev1.PodSpec, name string, replicas int32, scheduling *msoapi.SchedulingConfig) {
+	if scheduling != nil {
+		for k, v := range scheduling.NodeSelector {
+			podSpec.NodeSelector[k] = v
+		}
+		podSpec.Tolerations = scheduling.Tolerations
+		podSpec.TopologySpreadConstraints = scheduling.TopologySpreadConstraints
+		podSpec.PriorityClassName = scheduling.PriorityClassName
+	}
+	if scheduling != nil && scheduling.Affinity != nil {
+		podSpec.Affinity = scheduling.Affinity.DeepCopy()
+	}
+	if replicas > 1 {
+		podSpec.Affinity = addPodAntiAffinity(podSpec.Affinity, newPodAntiAffinity(name, scheduling))
+	}
+}
+
+// addPodAntiAffinity adds the terms of podAntiAffinity to the pod
+// anti-affinity of affinity.
+func addPodAntiAffinity(affinity *corev1.Affinity, podAntiAffinity *corev1.PodAntiAffinity) *corev1.Affinity {
+	if affinity == nil {
+		affinity = &corev1.Affinity{}
+	}
+	if affinity.PodAntiAffinity == nil {
+		affinity.PodAntiAffinity = podAntiAffinity
+		return affinity
+	}
+	affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+		affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+		podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution...,
+	)
+	affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+		affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+		podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution...,
+	)
+	return affinity
+}
+
+// newPodAntiAffinity returns the pod anti-affinity which spreads the replicas
+// of the querier across nodes, either as a requirement or as a preference
+// depending on the scheduling config of the querier.
+func newPodAntiAffinity(name string, scheduling *msoapi.SchedulingConfig) *corev1.PodAntiAffinity {
+	term := corev1.PodAffinityTerm{
+		TopologyKey: "kubernetes.io/hostname",
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"app.kubernetes.io/instance": name,
+			},
+		},
+	}
+
+	if scheduling != nil && scheduling.PodAntiAffinity == msoapi.PreferredPodAntiAffinity {
+		return &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{
+					Weight:          100,
+					PodAffinityTerm: term,
+				},
+			},
+		}
+	}
+
+	return &corev1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{term},
+	}
+}
+
+func newPodDisruptionBudget(name string, namespace string) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: policyv1.SchemeGroupVersion.String(),
+			Kind:       "PodDisruptionBudget",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    componentLabels(name),
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &intstr.IntOrString{
+				Type:   intstr.Int,
+				IntVal: 1,
+			},
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/instance": name,
+				},
+			},
+		},
+	}
+}
+
 func newServiceAccount(name string, namespace string) *corev1.ServiceAccount {
 	return &corev1.ServiceAccount{
 		TypeMeta: metav1.TypeMeta{
@@ -103,7 +392,7 @@ func newServiceAccount(name string, namespace string) *corev1.ServiceAccount {
 	}
 }
 
-func newService(name string, namespace string) *corev1.Service {
+func newService(name string, querier *msoapi.ThanosQuerier) *corev1.Service {
 	return &corev1.Service{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: corev1.SchemeGroupVersion.String(),
@@ -111,13 +400,20 @@ func newService(name string, namespace string) *corev1.Service {
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
-			Namespace: namespace,
+			Namespace: querier.Namespace,
+			Labels:    componentLabels(name),
 		},
 		Spec: corev1.ServiceSpec{
 			Ports: []corev1.ServicePort{
 				{
-					Port: 9090,
-					Name: "http",
+					Port:       httpPort(querier),
+					Name:       "http",
+					TargetPort: intstr.FromString("http"),
+				},
+				{
+					Port:       grpcPort(querier),
+					Name:       "grpc",
+					TargetPort: intstr.FromString("grpc"),
 				},
 			},
 			Selector: map[string]string{
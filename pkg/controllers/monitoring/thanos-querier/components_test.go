@@ -0,0 +1,185 @@
+package thanos_querier
+
+import (
+	"testing"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certs"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+	"golang.org/x/exp/slices"
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestTLS(t *testing.T) {
+	querier := &msoapi.ThanosQuerier{
+		ObjectMeta: metav1.ObjectMeta{Name: "querier", Namespace: "ns"},
+	}
+
+	deployment := newThanosQuerierDeployment("thanos-querier-querier", querier, nil, "thanos")
+	assert.Assert(t, !slices.Contains(deployment.Spec.Template.Spec.Containers[0].Args, "--grpc-client-tls-secure"))
+	assert.Equal(t, len(deployment.Spec.Template.Spec.Volumes), 0)
+	reconcilers := thanosComponentReconcilers(querier, nil, "thanos", nil)
+	_, ok := reconcilers[len(reconcilers)-1].(reconciler.Deleter)
+	assert.Assert(t, ok)
+
+	// the operator provisions the client certificate when no secret is referenced
+	querier.Spec.TLS = &msoapi.ThanosQuerierTLSConfig{}
+	deployment = newThanosQuerierDeployment("thanos-querier-querier", querier, nil, "thanos")
+	args := deployment.Spec.Template.Spec.Containers[0].Args
+	assert.Assert(t, slices.Contains(args, "--grpc-client-tls-secure"))
+	assert.Assert(t, slices.Contains(args, "--grpc-client-tls-ca=/etc/thanos/tls/ca.crt"))
+	assert.Assert(t, slices.Contains(args, "--grpc-client-server-name="+certs.ThanosSidecarServerName))
+	assert.Equal(t, deployment.Spec.Template.Spec.Volumes[0].Secret.SecretName, "thanos-querier-querier-tls")
+	reconcilers = thanosComponentReconcilers(querier, nil, "thanos", nil)
+	_, ok = reconcilers[len(reconcilers)-1].(certs.CertificateUpdater)
+	assert.Assert(t, ok)
+
+	querier.Spec.TLS = &msoapi.ThanosQuerierTLSConfig{
+		CertificateSecretName: "custom-tls",
+		ServerName:            "sidecar.example.com",
+	}
+	deployment = newThanosQuerierDeployment("thanos-querier-querier", querier, nil, "thanos")
+	assert.Assert(t, slices.Contains(deployment.Spec.Template.Spec.Containers[0].Args, "--grpc-client-server-name=sidecar.example.com"))
+	assert.Equal(t, deployment.Spec.Template.Spec.Volumes[0].Secret.SecretName, "custom-tls")
+	reconcilers = thanosComponentReconcilers(querier, nil, "thanos", nil)
+	_, ok = reconcilers[len(reconcilers)-1].(reconciler.Deleter)
+	assert.Assert(t, ok)
+}
+
+func TestReplicas(t *testing.T) {
+	querier := &msoapi.ThanosQuerier{
+		ObjectMeta: metav1.ObjectMeta{Name: "querier", Namespace: "ns"},
+	}
+
+	deployment := newThanosQuerierDeployment("thanos-querier-querier", querier, nil, "thanos")
+	assert.Equal(t, *deployment.Spec.Replicas, int32(1))
+	assert.Assert(t, deployment.Spec.Template.Spec.Affinity == nil)
+	assert.Assert(t, slices.Contains(deployment.Spec.Template.Spec.Containers[0].Args, "--log.level=info"))
+	assert.Equal(t, deployment.Spec.Template.Spec.Containers[0].Resources.Requests.Cpu().String(), "10m")
+	_, ok := thanosComponentReconcilers(querier, nil, "thanos", nil)[4].(reconciler.Deleter)
+	assert.Assert(t, ok)
+
+	querier.Spec.Replicas = func(i int32) *int32 { return &i }(2)
+	querier.Spec.LogLevel = msoapi.Debug
+	deployment = newThanosQuerierDeployment("thanos-querier-querier", querier, nil, "thanos")
+	assert.Equal(t, *deployment.Spec.Replicas, int32(2))
+	assert.Assert(t, slices.Contains(deployment.Spec.Template.Spec.Containers[0].Args, "--log.level=debug"))
+	antiAffinity := deployment.Spec.Template.Spec.Affinity.PodAntiAffinity
+	assert.Equal(t, len(antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution), 1)
+	assert.Equal(t, len(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution), 0)
+	_, ok = thanosComponentReconcilers(querier, nil, "thanos", nil)[4].(reconciler.Updater)
+	assert.Assert(t, ok)
+}
+
+func TestScheduling(t *testing.T) {
+	querier := &msoapi.ThanosQuerier{
+		ObjectMeta: metav1.ObjectMeta{Name: "querier", Namespace: "ns"},
+		Spec: msoapi.ThanosQuerierSpec{
+			Replicas: func(i int32) *int32 { return &i }(2),
+			Scheduling: &msoapi.SchedulingConfig{
+				NodeSelector:      map[string]string{"node-role.kubernetes.io/infra": ""},
+				Tolerations:       []corev1.Toleration{{Key: "node-role.kubernetes.io/infra", Operator: corev1.TolerationOpExists}},
+				PriorityClassName: "system-cluster-critical",
+				PodAntiAffinity:   msoapi.PreferredPodAntiAffinity,
+			},
+		},
+	}
+
+	podSpec := newThanosQuerierDeployment("thanos-querier-querier", querier, nil, "thanos").Spec.Template.Spec
+	assert.DeepEqual(t, podSpec.NodeSelector, map[string]string{
+		"kubernetes.io/os":              "linux",
+		"node-role.kubernetes.io/infra": "",
+	})
+	assert.DeepEqual(t, podSpec.Tolerations, querier.Spec.Scheduling.Tolerations)
+	assert.Equal(t, podSpec.PriorityClassName, "system-cluster-critical")
+	assert.Equal(t, len(podSpec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution), 0)
+	assert.Equal(t, len(podSpec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution), 1)
+
+	// the affinity of the user is kept with a single replica
+	querier.Spec.Replicas = func(i int32) *int32 { return &i }(1)
+	querier.Spec.Scheduling.Affinity = &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{Weight: 1}},
+		},
+	}
+	podSpec = newThanosQuerierDeployment("thanos-querier-querier", querier, nil, "thanos").Spec.Template.Spec
+	assert.DeepEqual(t, podSpec.Affinity, querier.Spec.Scheduling.Affinity)
+
+	querier.Spec.Replicas = func(i int32) *int32 { return &i }(2)
+	podSpec = newThanosQuerierDeployment("thanos-querier-querier", querier, nil, "thanos").Spec.Template.Spec
+	assert.Equal(t, len(podSpec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution), 2)
+	assert.Equal(t, len(querier.Spec.Scheduling.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution), 1)
+}
+
+func TestQueryArgs(t *testing.T) {
+	querier := &msoapi.ThanosQuerier{
+		Spec: msoapi.ThanosQuerierSpec{ReplicaLabels: []string{"rule_replica"}},
+	}
+	assert.DeepEqual(t, queryArgs(querier), []string{
+		"--query.replica-label=prometheus_replica",
+		"--query.replica-label=rule_replica",
+		"--query.auto-downsampling",
+	})
+
+	timeout := monv1.Duration("5m")
+	querier.Spec.Query = &msoapi.ThanosQueryConfig{
+		Timeout:                 &timeout,
+		MaxConcurrent:           func(i int32) *int32 { return &i }(10),
+		PartialResponseStrategy: msoapi.AbortPartialResponse,
+		DisableAutoDownsampling: true,
+		DisableDeduplication:    true,
+	}
+	assert.DeepEqual(t, queryArgs(querier), []string{
+		"--query.timeout=5m",
+		"--query.max-concurrent=10",
+		"--no-query.partial-response",
+	})
+}
+
+func TestPorts(t *testing.T) {
+	querier := &msoapi.ThanosQuerier{
+		ObjectMeta: metav1.ObjectMeta{Name: "querier", Namespace: "ns"},
+	}
+
+	container := newThanosQuerierDeployment("thanos-querier-querier", querier, nil, "thanos").Spec.Template.Spec.Containers[0]
+	assert.Assert(t, slices.Contains(container.Args, "--http-address=0.0.0.0:9090"))
+	assert.Assert(t, slices.Contains(container.Args, "--grpc-address=0.0.0.0:10901"))
+	assert.Equal(t, container.ReadinessProbe.HTTPGet.Path, "/-/ready")
+	assert.Equal(t, container.LivenessProbe.HTTPGet.Path, "/-/healthy")
+
+	querier.Spec.HTTPPort = func(i int32) *int32 { return &i }(8080)
+	querier.Spec.GRPCPort = func(i int32) *int32 { return &i }(8081)
+	container = newThanosQuerierDeployment("thanos-querier-querier", querier, nil, "thanos").Spec.Template.Spec.Containers[0]
+	assert.Assert(t, slices.Contains(container.Args, "--http-address=0.0.0.0:8080"))
+	assert.Assert(t, slices.Contains(container.Args, "--grpc-address=0.0.0.0:8081"))
+	assert.Equal(t, container.Ports[0].ContainerPort, int32(8080))
+	assert.Equal(t, container.Ports[1].ContainerPort, int32(8081))
+
+	svc := newService("thanos-querier-querier", querier)
+	assert.Equal(t, svc.Spec.Ports[0].Port, int32(8080))
+	assert.Equal(t, svc.Spec.Ports[0].TargetPort.StrVal, "http")
+	assert.Equal(t, svc.Spec.Ports[1].Port, int32(8081))
+	assert.Equal(t, svc.Spec.Ports[1].TargetPort.StrVal, "grpc")
+}
+
+// assertServiceMonitorSelects asserts that the ServiceMonitor selects the
+// Service in its namespace.
+func assertServiceMonitorSelects(t *testing.T, sm *monv1.ServiceMonitor, svc *corev1.Service) {
+	t.Helper()
+	selector, err := metav1.LabelSelectorAsSelector(&sm.Spec.Selector)
+	assert.NilError(t, err)
+	assert.Equal(t, sm.Namespace, svc.Namespace)
+	assert.Assert(t, selector.Matches(labels.Set(svc.Labels)), "%s doesn't select %s", sm.Name, svc.Name)
+}
+
+func TestServiceMonitor(t *testing.T) {
+	querier := &msoapi.ThanosQuerier{
+		ObjectMeta: metav1.ObjectMeta{Name: "querier", Namespace: "ns"},
+	}
+	name := "thanos-querier-querier"
+	assertServiceMonitorSelects(t, newServiceMonitor(name, querier.Namespace), newService(name, querier))
+}
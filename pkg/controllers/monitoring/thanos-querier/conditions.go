@@ -0,0 +1,117 @@
+package thanos_querier
+
+import (
+	"fmt"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	AvailableReason               = "ThanosQuerierAvailable"
+	ReconciledReason              = "ThanosQuerierReconciled"
+	FailedToReconcileReason       = "FailedToReconcile"
+	DeploymentNotFoundReason      = "DeploymentNotFound"
+	DeploymentNotAvailableReason  = "DeploymentNotAvailable"
+	DeploymentRollingOutReason    = "DeploymentRollingOut"
+	AvailableMessage              = "Thanos Querier is available"
+	SuccessfullyReconciledMessage = "Thanos Querier is successfully reconciled"
+	DeploymentNotFoundMessage     = "Thanos Querier deployment does not exist"
+	DeploymentNotObservedMessage  = "Thanos Querier deployment update has not been observed yet"
+)
+
+func updateConditions(querier *msoapi.ThanosQuerier, deployment *appsv1.Deployment, recError error) []msoapi.Condition {
+	return []msoapi.Condition{
+		updateAvailable(deployment, querier.Generation),
+		updateReconciled(querier.Generation, recError),
+	}
+}
+
+// updateAvailable returns the "Available" condition based on the rollout
+// status of the querier deployment. A nil deployment means that the
+// deployment doesn't exist.
+func updateAvailable(deployment *appsv1.Deployment, generation int64) msoapi.Condition {
+	ac := msoapi.Condition{
+		Type:               msoapi.AvailableCondition,
+		Status:             msoapi.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		ObservedGeneration: generation,
+	}
+
+	if deployment == nil {
+		ac.Reason = DeploymentNotFoundReason
+		ac.Message = DeploymentNotFoundMessage
+		return ac
+	}
+
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		ac.Reason = DeploymentRollingOutReason
+		ac.Message = DeploymentNotObservedMessage
+		return ac
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	if deployment.Status.UpdatedReplicas < replicas {
+		ac.Reason = DeploymentRollingOutReason
+		ac.Message = fmt.Sprintf("%d of %d replicas have been updated", deployment.Status.UpdatedReplicas, replicas)
+		return ac
+	}
+
+	available := getDeploymentCondition(deployment.Status.Conditions, appsv1.DeploymentAvailable)
+	if available == nil || available.Status != corev1.ConditionTrue {
+		ac.Reason = DeploymentNotAvailableReason
+		if available != nil {
+			ac.Message = available.Message
+		}
+		return ac
+	}
+
+	if deployment.Status.AvailableReplicas < deployment.Status.UpdatedReplicas {
+		ac.Reason = DeploymentRollingOutReason
+		ac.Message = fmt.Sprintf("%d of %d updated replicas are available", deployment.Status.AvailableReplicas, deployment.Status.UpdatedReplicas)
+		return ac
+	}
+
+	ac.Status = msoapi.ConditionTrue
+	ac.Reason = AvailableReason
+	ac.Message = AvailableMessage
+	return ac
+}
+
+// updateReconciled returns the "Reconciled" condition based on the provided
+// reconcile error.
+func updateReconciled(generation int64, reconcileErr error) msoapi.Condition {
+	if reconcileErr != nil {
+		return msoapi.Condition{
+			Type:               msoapi.ReconciledCondition,
+			Status:             msoapi.ConditionFalse,
+			Reason:             FailedToReconcileReason,
+			Message:            reconcileErr.Error(),
+			LastTransitionTime: metav1.Now(),
+			ObservedGeneration: generation,
+		}
+	}
+	return msoapi.Condition{
+		Type:               msoapi.ReconciledCondition,
+		Status:             msoapi.ConditionTrue,
+		Reason:             ReconciledReason,
+		Message:            SuccessfullyReconciledMessage,
+		LastTransitionTime: metav1.Now(),
+		ObservedGeneration: generation,
+	}
+}
+
+func getDeploymentCondition(conditions []appsv1.DeploymentCondition, t appsv1.DeploymentConditionType) *appsv1.DeploymentCondition {
+	for i := range conditions {
+		if conditions[i].Type == t {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
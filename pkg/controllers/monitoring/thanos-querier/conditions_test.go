@@ -0,0 +1,128 @@
+package thanos_querier
+
+import (
+	"fmt"
+	"testing"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUpdateAvailable(t *testing.T) {
+	replicas := int32(2)
+	newDeployment := func(status appsv1.DeploymentStatus) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Generation: 2},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     status,
+		}
+	}
+	availableCondition := []appsv1.DeploymentCondition{{
+		Type:   appsv1.DeploymentAvailable,
+		Status: corev1.ConditionTrue,
+	}}
+
+	tt := []struct {
+		name           string
+		deployment     *appsv1.Deployment
+		expectedStatus msoapi.ConditionStatus
+		expectedReason string
+	}{
+		{
+			name:           "deployment does not exist",
+			deployment:     nil,
+			expectedStatus: msoapi.ConditionFalse,
+			expectedReason: DeploymentNotFoundReason,
+		},
+		{
+			name: "deployment update not observed",
+			deployment: newDeployment(appsv1.DeploymentStatus{
+				ObservedGeneration: 1,
+				UpdatedReplicas:    2,
+				AvailableReplicas:  2,
+				Conditions:         availableCondition,
+			}),
+			expectedStatus: msoapi.ConditionFalse,
+			expectedReason: DeploymentRollingOutReason,
+		},
+		{
+			name: "replicas not updated",
+			deployment: newDeployment(appsv1.DeploymentStatus{
+				ObservedGeneration: 2,
+				UpdatedReplicas:    1,
+				AvailableReplicas:  2,
+				Conditions:         availableCondition,
+			}),
+			expectedStatus: msoapi.ConditionFalse,
+			expectedReason: DeploymentRollingOutReason,
+		},
+		{
+			name: "deployment not available",
+			deployment: newDeployment(appsv1.DeploymentStatus{
+				ObservedGeneration: 2,
+				UpdatedReplicas:    2,
+				Conditions: []appsv1.DeploymentCondition{{
+					Type:   appsv1.DeploymentAvailable,
+					Status: corev1.ConditionFalse,
+				}},
+			}),
+			expectedStatus: msoapi.ConditionFalse,
+			expectedReason: DeploymentNotAvailableReason,
+		},
+		{
+			name: "updated replicas not available",
+			deployment: newDeployment(appsv1.DeploymentStatus{
+				ObservedGeneration: 2,
+				UpdatedReplicas:    2,
+				AvailableReplicas:  1,
+				Conditions:         availableCondition,
+			}),
+			expectedStatus: msoapi.ConditionFalse,
+			expectedReason: DeploymentRollingOutReason,
+		},
+		{
+			name: "deployment rolled out",
+			deployment: newDeployment(appsv1.DeploymentStatus{
+				ObservedGeneration: 2,
+				UpdatedReplicas:    2,
+				AvailableReplicas:  2,
+				Conditions:         availableCondition,
+			}),
+			expectedStatus: msoapi.ConditionTrue,
+			expectedReason: AvailableReason,
+		},
+	}
+
+	for _, test := range tt {
+		res := updateAvailable(test.deployment, 3)
+		assert.Equal(t, res.Type, msoapi.AvailableCondition, test.name)
+		assert.Equal(t, res.Status, test.expectedStatus, test.name)
+		assert.Equal(t, res.Reason, test.expectedReason, test.name)
+		assert.Equal(t, res.ObservedGeneration, int64(3), test.name)
+	}
+}
+
+func TestUpdateReconciled(t *testing.T) {
+	res := updateReconciled(1, nil)
+	expected := msoapi.Condition{
+		Type:               msoapi.ReconciledCondition,
+		Status:             msoapi.ConditionTrue,
+		ObservedGeneration: 1,
+		Reason:             ReconciledReason,
+		Message:            SuccessfullyReconciledMessage,
+	}
+	assert.Check(t, expected.Equal(res), "expected:\n %v\n and got:\n %v\n", expected, res)
+
+	res = updateReconciled(1, fmt.Errorf("invalid selector"))
+	expected = msoapi.Condition{
+		Type:               msoapi.ReconciledCondition,
+		Status:             msoapi.ConditionFalse,
+		ObservedGeneration: 1,
+		Reason:             FailedToReconcileReason,
+		Message:            "invalid selector",
+	}
+	assert.Check(t, expected.Equal(res), "expected:\n %v\n and got:\n %v\n", expected, res)
+}
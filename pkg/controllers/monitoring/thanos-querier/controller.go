@@ -18,15 +18,21 @@ import (
 	"time"
 
 	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certs"
+	"github.com/rhobs/observability-operator/pkg/conditions"
+	"github.com/rhobs/observability-operator/pkg/grafana"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -40,8 +46,25 @@ import (
 
 type resourceManager struct {
 	client.Client
-	scheme *runtime.Scheme
-	logger logr.Logger
+	scheme             *runtime.Scheme
+	logger             logr.Logger
+	recorder           record.EventRecorder
+	events             reconciler.EventRecorder
+	thanosImage        string
+	caProvider         *certs.CAProvider
+	grafanaDataSources *grafana.DataSourceWatcher
+}
+
+// stackSelectionFailedReason is the reason of the events recorded when the
+// stacks selected by a querier can't be found, e.g. due to an invalid selector.
+const stackSelectionFailedReason = "StackSelectionFailed"
+
+// Options allows for controller options to be set
+type Options struct {
+	// ThanosImage is the container image of Thanos Querier
+	ThanosImage string
+	// CAProvider provides the CA signing the client certificates of queriers
+	CAProvider *certs.CAProvider
 }
 
 // RBAC for watching monitoring stacks
@@ -55,36 +78,73 @@ type resourceManager struct {
 // RBAC for managing deployments
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=list;watch;create;update;patch;delete
 
+// RBAC for managing the statefulsets of the store gateway and the compactor
+//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=list;watch;create;update;patch;delete
+
+// RBAC for managing pod disruption budgets
+//+kubebuilder:rbac:groups="policy",resources=poddisruptionbudgets,verbs=list;watch;create;update;delete;patch
+
 // RBAC for managing core resources
 //+kubebuilder:rbac:groups=core,resources=services;serviceaccounts,verbs=list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+
+// RBAC for managing Grafana datasources
+//+kubebuilder:rbac:groups=integreatly.org,resources=grafanadatasources,verbs=get;list;watch;create;update;delete;patch
+
+// RBAC for resolving namespace selectors
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=list;watch
+
+// RBAC for recording events
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // RBAC for managing Prometheus Operator CRs
 //+kubebuilder:rbac:groups=monitoring.rhobs,resources=servicemonitors,verbs=list;watch;create;update;patch;delete
 
 // RegisterWithManager registers the controller with Manager
-func RegisterWithManager(mgr ctrl.Manager) error {
+func RegisterWithManager(mgr ctrl.Manager, opts Options) error {
 	logger := ctrl.Log.WithName("thanos-querier")
+	recorder := mgr.GetEventRecorderFor("thanos-querier")
 	rm := &resourceManager{
-		Client: mgr.GetClient(),
-		scheme: mgr.GetScheme(),
-		logger: logger,
+		Client:      mgr.GetClient(),
+		scheme:      mgr.GetScheme(),
+		logger:      logger,
+		recorder:    recorder,
+		events:      reconciler.NewEventRecorder(recorder),
+		thanosImage: opts.ThanosImage,
+		caProvider:  opts.CAProvider,
 	}
 
-	p := predicate.GenerationChangedPredicate{}
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&msoapi.ThanosQuerier{}).
-		Owns(&appsv1.Deployment{}).WithEventFilter(p).
-		Owns(&corev1.ServiceAccount{}).WithEventFilter(p).
-		Owns(&corev1.Service{}).WithEventFilter(p).
+	// Only react to generation changes of the querier and its children, except
+	// for the Deployment whose status is reflected in the querier status.
+	generationChanged := builder.WithPredicates(predicate.GenerationChangedPredicate{})
+	c, err := ctrl.NewControllerManagedBy(mgr).
+		For(&msoapi.ThanosQuerier{}, generationChanged).
+		Owns(&appsv1.Deployment{}, builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
+		Owns(&appsv1.StatefulSet{}, generationChanged).
+		Owns(&corev1.ServiceAccount{}, generationChanged).
+		Owns(&corev1.Service{}, generationChanged).
+		Owns(&policyv1.PodDisruptionBudget{}, generationChanged).
 		Watches(
 			&source.Kind{Type: &msoapi.MonitoringStack{}},
 			handler.EnqueueRequestsFromMapFunc(rm.findQueriersForMonitoringStack),
-			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+			// the status of the stacks isn't used, while their labels are
+			// matched by the stack selector of the queriers
+			builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{})),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.Namespace{}},
+			handler.EnqueueRequestsFromMapFunc(rm.findQueriersForNamespace),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
 		).
-		Complete(rm)
+		Build(rm)
+	if err != nil {
+		return err
+	}
+	rm.grafanaDataSources = grafana.NewDataSourceWatcher(mgr.GetRESTMapper(), c, &msoapi.ThanosQuerier{}, logger)
+	return nil
 }
 
-func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (rm *resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := rm.logger.WithValues("querier", req.NamespacedName)
 	logger.Info("Reconciling Thanos Querier")
 
@@ -97,16 +157,48 @@ func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		return ctrl.Result{}, err
 	}
 
-	sidecarServices, err := rm.findSidecarServices(ctx, querier)
+	stacks, sidecarServices, err := rm.findSidecarServices(ctx, querier)
 	if client.IgnoreNotFound(err) != nil {
 		// we encountered an error other then NotFound, don't try to delete
 		// resources for this querier and reschedule reconcile
+		rm.recorder.Event(querier, corev1.EventTypeWarning, stackSelectionFailedReason, err.Error())
+		rm.updateStatus(ctx, req, querier, err)
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, err
 	}
+	endpoints := append(sidecarServices, additionalEndpointUrls(querier)...)
+	endpoints = append(endpoints, storeGatewayEndpointUrls(querier)...)
+	querier.Status.SelectedStacks = stacks
+	querier.Status.Endpoints = endpoints
 
-	reconcilers := thanosComponentReconcilers(querier, sidecarServices)
+	var ca *certs.CA
+	if provisionsCertificate(querier) {
+		ca, err = rm.caProvider.CA(ctx)
+		if apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err) {
+			logger.V(8).Info("skipping reconcile error", "err", err)
+			return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+		}
+		if err != nil {
+			return rm.updateStatus(ctx, req, querier, err), err
+		}
+	}
+
+	reconcilers := thanosComponentReconcilers(querier, endpoints, rm.thanosImage, ca)
+	frontendReconcilers, err := queryFrontendReconcilers(querier, rm.thanosImage)
+	if err != nil {
+		return rm.updateStatus(ctx, req, querier, err), err
+	}
+	reconcilers = append(reconcilers, frontendReconcilers...)
+	reconcilers = append(reconcilers, objectStorageReconcilers(querier, rm.thanosImage)...)
+
+	grafanaSupported, err := rm.grafanaDataSources.EnsureWatch()
+	if err != nil {
+		return rm.updateStatus(ctx, req, querier, err), err
+	}
+	if grafanaSupported {
+		reconcilers = append(reconcilers, reconciler.NewUpdater(newGrafanaDataSource(querier), querier))
+	}
 	for _, reconciler := range reconcilers {
-		err := reconciler.Reconcile(ctx, rm, rm.scheme)
+		err := rm.events.Reconcile(ctx, rm, rm.scheme, querier, reconciler)
 		// handle creation / updation errors that can happen due to a stale cache by
 		// retrying after some time.
 		if apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err) {
@@ -114,42 +206,148 @@ func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 			return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
 		}
 		if err != nil {
-			return ctrl.Result{}, err
+			return rm.updateStatus(ctx, req, querier, err), err
 		}
 	}
-	return ctrl.Result{}, nil
+	return rm.updateStatus(ctx, req, querier, nil), nil
+}
+
+// updateStatus sets the conditions of the querier based on the reconcile
+// error and the status of the querier deployment and writes the status.
+func (rm resourceManager) updateStatus(ctx context.Context, req ctrl.Request, querier *msoapi.ThanosQuerier, recError error) ctrl.Result {
+	logger := rm.logger.WithValues("querier", req.NamespacedName)
+
+	var deployment *appsv1.Deployment
+	dep := &appsv1.Deployment{}
+	key := client.ObjectKey{
+		Name:      "thanos-querier-" + querier.Name,
+		Namespace: querier.Namespace,
+	}
+	err := rm.Get(ctx, key, dep)
+	switch {
+	case err == nil:
+		deployment = dep
+	case !apierrors.IsNotFound(err):
+		logger.Info("Failed to get thanos querier deployment", "err", err)
+		return ctrl.Result{RequeueAfter: 2 * time.Second}
+	}
+
+	// the status of the cached querier is compared since the reconciliation
+	// already set the selected stacks and endpoints
+	current := &msoapi.ThanosQuerier{}
+	if err := rm.Get(ctx, client.ObjectKeyFromObject(querier), current); err != nil {
+		logger.Info("Failed to get thanos querier", "err", err)
+		return ctrl.Result{RequeueAfter: 2 * time.Second}
+	}
+
+	querier.Status.ObservedGeneration = querier.Generation
+	querier.Status.Conditions = conditions.Merge(current.Status.Conditions, updateConditions(querier, deployment, recError))
+	err = conditions.UpdateStatus(ctx, rm, rm.recorder, querier, current.Status, querier.Status, current.Status.Conditions, querier.Status.Conditions)
+	if err != nil {
+		logger.Info("Failed to update status", "err", err)
+		return ctrl.Result{RequeueAfter: 2 * time.Second}
+	}
+	return ctrl.Result{}
 }
 
 // Given a ThanosQuerier object, find the matching MonitoringStacks, extract the
-// sidecar service and return a list of urls for those sidecar services.
-func (rm resourceManager) findSidecarServices(ctx context.Context, tQuerier *msoapi.ThanosQuerier) ([]string, error) {
+// sidecar service and return the selected stacks along with a list of urls for
+// those sidecar services.
+func (rm resourceManager) findSidecarServices(ctx context.Context, tQuerier *msoapi.ThanosQuerier) ([]msoapi.SelectedMonitoringStack, []string, error) {
 	logger := rm.logger.WithValues("selector", tQuerier.Spec.Selector)
 
+	selector, err := metav1.LabelSelectorAsSelector(&tQuerier.Spec.Selector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid selector: %w", err)
+	}
+
 	msList := &msoapi.MonitoringStackList{}
-	selector, _ := metav1.LabelSelectorAsSelector(&tQuerier.Spec.Selector)
 	opts := []client.ListOption{
 		client.MatchingLabelsSelector{Selector: selector},
 	}
 
-	var sidecarUrls []string
+	var (
+		stacks      []msoapi.SelectedMonitoringStack
+		sidecarUrls []string
+	)
 	if err := rm.List(ctx, msList, opts...); err != nil {
 		logger.Info("Couldn't find any MonitoringStack")
-		return sidecarUrls, err
+		return stacks, sidecarUrls, err
 	}
 	logger.Info("Found MonitoringStacks list", "length", len(msList.Items))
+
+	var namespaceLabels map[string]map[string]string
+	if tQuerier.Spec.NamespaceSelector.LabelSelector != nil {
+		namespaceLabels, err = rm.namespaceLabels(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 	for _, ms := range msList.Items {
-		if tQuerier.MatchesNamespace(ms.Namespace) {
+		matches, err := tQuerier.MatchesNamespace(ms.Namespace, namespaceLabels[ms.Namespace])
+		if err != nil {
+			return nil, nil, err
+		}
+		if matches {
+			stacks = append(stacks, msoapi.SelectedMonitoringStack{
+				Name:      ms.Name,
+				Namespace: ms.Namespace,
+			})
 			serviceName := ms.Name + "-thanos-sidecar"
 			sidecarUrls = append(sidecarUrls, getEndpointUrl(serviceName, ms.Namespace))
 		}
 	}
 
-	return sidecarUrls, nil
+	return stacks, sidecarUrls, nil
+}
+
+// namespaceLabels returns the labels of all namespaces by name.
+func (rm resourceManager) namespaceLabels(ctx context.Context) (map[string]map[string]string, error) {
+	namespaces := &corev1.NamespaceList{}
+	if err := rm.List(ctx, namespaces); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	namespaceLabels := make(map[string]map[string]string, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		namespaceLabels[ns.Name] = ns.Labels
+	}
+	return namespaceLabels, nil
 }
 
 // Given a Service object, return a url to use as value for --store/--endpoint.
 func getEndpointUrl(serviceName string, namespace string) string {
-	return fmt.Sprintf("dnssrv+_grpc._tcp.%s.%s.svc.cluster.local", serviceName, namespace)
+	return getServiceEndpointUrl(serviceName, namespace, "grpc")
+}
+
+// getServiceEndpointUrl returns the url of the endpoints behind the named
+// port of a Service.
+func getServiceEndpointUrl(serviceName string, namespace string, port string) string {
+	return fmt.Sprintf("dnssrv+_%s._tcp.%s.%s.svc.cluster.local", port, serviceName, namespace)
+}
+
+// additionalEndpointUrls returns the urls of the additional endpoints of the
+// querier.
+func additionalEndpointUrls(querier *msoapi.ThanosQuerier) []string {
+	var urls []string
+	for _, endpoint := range querier.Spec.AdditionalEndpoints {
+		switch {
+		case endpoint.Address != "":
+			urls = append(urls, endpoint.Address)
+		case endpoint.DNSSRVName != "":
+			urls = append(urls, "dnssrv+"+endpoint.DNSSRVName)
+		case endpoint.Service != nil:
+			namespace := endpoint.Service.Namespace
+			if namespace == "" {
+				namespace = querier.Namespace
+			}
+			port := endpoint.Service.Port
+			if port == "" {
+				port = "grpc"
+			}
+			urls = append(urls, getServiceEndpointUrl(endpoint.Service.Name, namespace, port))
+		}
+	}
+	return urls
 }
 
 // Find all ThanosQueriers, whose Selector fits the given MonitoringStack and
@@ -182,3 +380,32 @@ func (rm resourceManager) findQueriersForMonitoringStack(ms client.Object) []rec
 	}
 	return requests
 }
+
+// Find all ThanosQueriers selecting namespaces by labels and return a list of
+// reconcile requests, one for each ThanosQuerier. The labels of the namespace
+// before the change aren't known, so all of them have to be reconciled.
+func (rm resourceManager) findQueriersForNamespace(ns client.Object) []reconcile.Request {
+	logger := rm.logger.WithValues("Namespace", ns.GetName())
+	queriers := &msoapi.ThanosQuerierList{}
+	if err := rm.List(context.TODO(), queriers); err != nil {
+		logger.Error(err, "Failed to list Thanosqueriers")
+		return []reconcile.Request{}
+	}
+
+	var requests []reconcile.Request
+	for _, item := range queriers.Items {
+		if item.Spec.NamespaceSelector.LabelSelector == nil {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      item.GetName(),
+				Namespace: item.GetNamespace(),
+			},
+		})
+	}
+	if len(requests) > 0 {
+		logger.Info("watched Namespace labels changed, scheduling sync", "queriers", len(requests))
+	}
+	return requests
+}
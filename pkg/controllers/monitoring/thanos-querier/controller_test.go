@@ -0,0 +1,30 @@
+package thanos_querier
+
+import (
+	"testing"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAdditionalEndpointUrls(t *testing.T) {
+	querier := &msoapi.ThanosQuerier{
+		ObjectMeta: metav1.ObjectMeta{Name: "querier", Namespace: "ns"},
+		Spec: msoapi.ThanosQuerierSpec{
+			AdditionalEndpoints: []msoapi.ThanosEndpoint{
+				{Address: "thanos-store.example.com:10901"},
+				{DNSSRVName: "_grpc._tcp.thanos-store.monitoring.svc.cluster.local"},
+				{Service: &msoapi.ThanosEndpointService{Name: "thanos-ruler"}},
+				{Service: &msoapi.ThanosEndpointService{Name: "thanos-receive", Namespace: "monitoring", Port: "store"}},
+			},
+		},
+	}
+
+	assert.DeepEqual(t, additionalEndpointUrls(querier), []string{
+		"thanos-store.example.com:10901",
+		"dnssrv+_grpc._tcp.thanos-store.monitoring.svc.cluster.local",
+		"dnssrv+_grpc._tcp.thanos-ruler.ns.svc.cluster.local",
+		"dnssrv+_store._tcp.thanos-receive.monitoring.svc.cluster.local",
+	})
+}
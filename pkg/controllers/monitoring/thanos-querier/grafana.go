@@ -0,0 +1,21 @@
+package thanos_querier
+
+import (
+	"fmt"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/grafana"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// newGrafanaDataSource returns the Grafana datasource of the querier. Queries
+// go through the query frontend when it is enabled.
+func newGrafanaDataSource(querier *msoapi.ThanosQuerier) *unstructured.Unstructured {
+	name := "thanos-querier-" + querier.Name
+	url := fmt.Sprintf("http://%s.%s.svc:%d", name, querier.Namespace, httpPort(querier))
+	if querier.Spec.QueryFrontend != nil {
+		url = fmt.Sprintf("http://thanos-query-frontend-%s.%s.svc:9090", querier.Name, querier.Namespace)
+	}
+	return grafana.NewPrometheusDataSource(name, querier.Namespace, componentLabels(name), url, nil)
+}
@@ -0,0 +1,345 @@
+package thanos_querier
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	objectStorageConfigDir = "/etc/thanos/objstore"
+	thanosDataDir          = "/var/thanos/data"
+	thanosDataVolume       = "data"
+	objectStorageVolume    = "objstore-config"
+
+	// thanosUser is the nobody user the Thanos image runs as, which has to
+	// own the data volumes.
+	thanosUser = int64(65534)
+)
+
+func storeGatewayName(querier *msoapi.ThanosQuerier) string {
+	return "thanos-store-gateway-" + querier.Name
+}
+
+func compactorName(querier *msoapi.ThanosQuerier) string {
+	return "thanos-compactor-" + querier.Name
+}
+
+func storeGatewayEnabled(querier *msoapi.ThanosQuerier) bool {
+	return querier.Spec.ObjectStorage != nil && querier.Spec.ObjectStorage.StoreGateway != nil
+}
+
+func compactorEnabled(querier *msoapi.ThanosQuerier) bool {
+	return querier.Spec.ObjectStorage != nil && querier.Spec.ObjectStorage.Compactor != nil
+}
+
+func storeGatewayReplicas(querier *msoapi.ThanosQuerier) int32 {
+	if !storeGatewayEnabled(querier) || querier.Spec.ObjectStorage.StoreGateway.Replicas == nil {
+		return 1
+	}
+	return *querier.Spec.ObjectStorage.StoreGateway.Replicas
+}
+
+// storeGatewayEndpointUrls returns the url of the store gateway of the
+// querier, when it is enabled.
+func storeGatewayEndpointUrls(querier *msoapi.ThanosQuerier) []string {
+	if !storeGatewayEnabled(querier) {
+		return nil
+	}
+	return []string{getEndpointUrl(storeGatewayName(querier), querier.Namespace)}
+}
+
+// objectStorageReconcilers returns the reconcilers of the store gateway and
+// the compactor of the querier, which delete them when they are disabled.
+func objectStorageReconcilers(querier *msoapi.ThanosQuerier, thanosImage string) []reconciler.Reconciler {
+	// the image of the querier overrides the image configured for the operator
+	if querier.Spec.Image != "" {
+		thanosImage = querier.Spec.Image
+	}
+
+	storeName := storeGatewayName(querier)
+	store := newStatefulSet(storeName, querier.Namespace)
+	if storeGatewayEnabled(querier) {
+		store = newStoreGatewayStatefulSet(storeName, querier, thanosImage)
+	}
+
+	compactName := compactorName(querier)
+	compactor := newStatefulSet(compactName, querier.Namespace)
+	if compactorEnabled(querier) {
+		compactor = newCompactorStatefulSet(compactName, querier, thanosImage)
+	}
+
+	return []reconciler.Reconciler{
+		reconciler.NewOptionalUpdater(store, querier, storeGatewayEnabled(querier)),
+		reconciler.NewOptionalUpdater(newStoreGatewayService(storeName, querier.Namespace), querier, storeGatewayEnabled(querier)),
+		reconciler.NewOptionalUpdater(newServiceMonitor(storeName, querier.Namespace), querier, storeGatewayEnabled(querier)),
+		reconciler.NewOptionalUpdater(newPodDisruptionBudget(storeName, querier.Namespace), querier,
+			storeGatewayEnabled(querier) && storeGatewayReplicas(querier) > 1),
+		reconciler.NewOptionalUpdater(compactor, querier, compactorEnabled(querier)),
+		reconciler.NewOptionalUpdater(newCompactorService(compactName, querier.Namespace), querier, compactorEnabled(querier)),
+		reconciler.NewOptionalUpdater(newServiceMonitor(compactName, querier.Namespace), querier, compactorEnabled(querier)),
+	}
+}
+
+func newStatefulSet(name string, namespace string) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "StatefulSet",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    componentLabels(name),
+		},
+	}
+}
+
+func newStoreGatewayStatefulSet(name string, querier *msoapi.ThanosQuerier, thanosImage string) *appsv1.StatefulSet {
+	config := querier.Spec.ObjectStorage.StoreGateway
+
+	args := []string{
+		"store",
+		"--grpc-address=0.0.0.0:10901",
+		"--http-address=0.0.0.0:10902",
+		"--log.format=logfmt",
+		fmt.Sprintf("--log.level=%s", querierLogLevel(querier)),
+		fmt.Sprintf("--data-dir=%s", thanosDataDir),
+		fmt.Sprintf("--objstore.config-file=%s", objectStorageConfigFile(querier)),
+	}
+
+	replicas := storeGatewayReplicas(querier)
+
+	ports := []corev1.ContainerPort{
+		{
+			ContainerPort: 10901,
+			Name:          "grpc",
+		},
+		{
+			ContainerPort: 10902,
+			Name:          "http",
+		},
+	}
+	sts := newThanosStatefulSet(name, querier, "thanos-store-gateway", thanosImage, args, ports,
+		defaultResources(config.Resources), config.PersistentVolumeClaim)
+	sts.Spec.Replicas = &replicas
+	applyScheduling(&sts.Spec.Template.Spec, name, replicas, querier.Spec.Scheduling)
+	return sts
+}
+
+func newCompactorStatefulSet(name string, querier *msoapi.ThanosQuerier, thanosImage string) *appsv1.StatefulSet {
+	config := querier.Spec.ObjectStorage.Compactor
+
+	args := []string{
+		"compact",
+		"--wait",
+		"--http-address=0.0.0.0:10902",
+		"--log.format=logfmt",
+		fmt.Sprintf("--log.level=%s", querierLogLevel(querier)),
+		fmt.Sprintf("--data-dir=%s", thanosDataDir),
+		fmt.Sprintf("--objstore.config-file=%s", objectStorageConfigFile(querier)),
+	}
+	args = append(args, retentionArgs(config.Retention)...)
+
+	ports := []corev1.ContainerPort{
+		{
+			ContainerPort: 10902,
+			Name:          "http",
+		},
+	}
+	sts := newThanosStatefulSet(name, querier, "thanos-compactor", thanosImage, args, ports,
+		defaultResources(config.Resources), config.PersistentVolumeClaim)
+	// concurrent compactors corrupt the bucket, so there is always a single one
+	replicas := int32(1)
+	sts.Spec.Replicas = &replicas
+	applyScheduling(&sts.Spec.Template.Spec, name, replicas, querier.Spec.Scheduling)
+	return sts
+}
+
+// retentionArgs returns the flags of the compactor retaining blocks by
+// resolution. Unset resolutions are retained forever.
+func retentionArgs(retention *msoapi.RetentionByResolution) []string {
+	if retention == nil {
+		return nil
+	}
+	var args []string
+	if retention.Raw != "" {
+		args = append(args, fmt.Sprintf("--retention.resolution-raw=%s", retention.Raw))
+	}
+	if retention.FiveMinutes != "" {
+		args = append(args, fmt.Sprintf("--retention.resolution-5m=%s", retention.FiveMinutes))
+	}
+	if retention.OneHour != "" {
+		args = append(args, fmt.Sprintf("--retention.resolution-1h=%s", retention.OneHour))
+	}
+	return args
+}
+
+func objectStorageConfigFile(querier *msoapi.ThanosQuerier) string {
+	return path.Join(objectStorageConfigDir, querier.Spec.ObjectStorage.Secret.Key)
+}
+
+// newThanosStatefulSet returns a StatefulSet running a Thanos component
+// reading the object storage config of the querier. The data directory is
+// backed by a persistent volume claim template when pvc is set or by an
+// emptyDir volume otherwise.
+func newThanosStatefulSet(name string, querier *msoapi.ThanosQuerier, containerName string, thanosImage string, args []string, ports []corev1.ContainerPort, resources corev1.ResourceRequirements, pvc *corev1.PersistentVolumeClaimSpec) *appsv1.StatefulSet {
+	sts := newStatefulSet(name, querier.Namespace)
+	sts.Spec = appsv1.StatefulSetSpec{
+		ServiceName: name,
+		Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"app.kubernetes.io/instance": name,
+			},
+		},
+		Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: querier.Namespace,
+				Labels:    componentLabels(name),
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:      containerName,
+						Args:      args,
+						Image:     thanosImage,
+						Resources: resources,
+						Ports:     ports,
+						LivenessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path: "/-/healthy",
+									Port: intstr.FromString("http"),
+								},
+							},
+							PeriodSeconds:    30,
+							FailureThreshold: 4,
+						},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path: "/-/ready",
+									Port: intstr.FromString("http"),
+								},
+							},
+							PeriodSeconds:    5,
+							FailureThreshold: 20,
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{
+								Name:      thanosDataVolume,
+								MountPath: thanosDataDir,
+							},
+							{
+								Name:      objectStorageVolume,
+								MountPath: objectStorageConfigDir,
+								ReadOnly:  true,
+							},
+						},
+						TerminationMessagePolicy: "FallbackToLogsOnError",
+					},
+				},
+				Volumes: []corev1.Volume{
+					{
+						Name: objectStorageVolume,
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{
+								SecretName: querier.Spec.ObjectStorage.Secret.Name,
+							},
+						},
+					},
+				},
+				SecurityContext: &corev1.PodSecurityContext{
+					RunAsNonRoot: func(b bool) *bool { return &b }(true),
+					RunAsUser:    func(i int64) *int64 { return &i }(thanosUser),
+					FSGroup:      func(i int64) *int64 { return &i }(thanosUser),
+				},
+				NodeSelector: map[string]string{
+					"kubernetes.io/os": "linux",
+				},
+			},
+		},
+	}
+
+	if pvc == nil {
+		sts.Spec.Template.Spec.Volumes = append(sts.Spec.Template.Spec.Volumes, corev1.Volume{
+			Name: thanosDataVolume,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
+		return sts
+	}
+	sts.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{
+		{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: corev1.SchemeGroupVersion.String(),
+				Kind:       "PersistentVolumeClaim",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   thanosDataVolume,
+				Labels: componentLabels(name),
+			},
+			Spec: *pvc,
+		},
+	}
+	return sts
+}
+
+// newStoreGatewayService returns the headless Service of the store gateway,
+// whose SRV records resolve to the addresses of all its pods.
+func newStoreGatewayService(name string, namespace string) *corev1.Service {
+	return newHeadlessService(name, namespace, []corev1.ServicePort{
+		{
+			Port:       10901,
+			Name:       "grpc",
+			TargetPort: intstr.FromString("grpc"),
+		},
+		{
+			Port:       10902,
+			Name:       "http",
+			TargetPort: intstr.FromString("http"),
+		},
+	})
+}
+
+func newCompactorService(name string, namespace string) *corev1.Service {
+	return newHeadlessService(name, namespace, []corev1.ServicePort{
+		{
+			Port:       10902,
+			Name:       "http",
+			TargetPort: intstr.FromString("http"),
+		},
+	})
+}
+
+func newHeadlessService(name string, namespace string, ports []corev1.ServicePort) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    componentLabels(name),
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: ports,
+			Selector: map[string]string{
+				"app.kubernetes.io/instance": name,
+			},
+			ClusterIP: corev1.ClusterIPNone,
+			Type:      "ClusterIP",
+		},
+	}
+}
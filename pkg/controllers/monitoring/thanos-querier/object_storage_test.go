@@ -0,0 +1,153 @@
+package thanos_querier
+
+import (
+	"context"
+	"testing"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+	"golang.org/x/exp/slices"
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestObjectStorage(t *testing.T) {
+	querier := &msoapi.ThanosQuerier{
+		ObjectMeta: metav1.ObjectMeta{Name: "querier", Namespace: "ns"},
+	}
+
+	for _, r := range objectStorageReconcilers(querier, "thanos") {
+		_, ok := r.(reconciler.Deleter)
+		assert.Assert(t, ok)
+	}
+	assert.Assert(t, storeGatewayEndpointUrls(querier) == nil)
+
+	querier.Spec.ObjectStorage = &msoapi.ThanosObjectStorageConfig{
+		Secret: corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "objstore"},
+			Key:                  "thanos.yaml",
+		},
+		StoreGateway: &msoapi.StoreGatewayConfig{
+			Replicas: func(i int32) *int32 { return &i }(2),
+		},
+		Compactor: &msoapi.CompactorConfig{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+				},
+			},
+			Retention: &msoapi.RetentionByResolution{Raw: "30d", OneHour: "1y"},
+		},
+	}
+	for _, r := range objectStorageReconcilers(querier, "thanos") {
+		_, ok := r.(reconciler.Updater)
+		assert.Assert(t, ok)
+	}
+	assert.DeepEqual(t, storeGatewayEndpointUrls(querier), []string{
+		"dnssrv+_grpc._tcp.thanos-store-gateway-querier.ns.svc.cluster.local",
+	})
+
+	store := newStoreGatewayStatefulSet("thanos-store-gateway-querier", querier, "thanos")
+	assert.Equal(t, *store.Spec.Replicas, int32(2))
+	assert.Assert(t, slices.Contains(store.Spec.Template.Spec.Containers[0].Args, "--objstore.config-file=/etc/thanos/objstore/thanos.yaml"))
+	assert.Equal(t, len(store.Spec.VolumeClaimTemplates), 0)
+	assert.Equal(t, store.Spec.Template.Spec.Volumes[0].Secret.SecretName, "objstore")
+	assert.Assert(t, store.Spec.Template.Spec.Volumes[1].EmptyDir != nil)
+	assert.Assert(t, store.Spec.Template.Spec.Affinity != nil)
+
+	compactor := newCompactorStatefulSet("thanos-compactor-querier", querier, "thanos")
+	assert.Equal(t, *compactor.Spec.Replicas, int32(1))
+	assert.Equal(t, len(compactor.Spec.VolumeClaimTemplates), 1)
+	assert.Equal(t, len(compactor.Spec.Template.Spec.Volumes), 1)
+	args := compactor.Spec.Template.Spec.Containers[0].Args
+	assert.Assert(t, slices.Contains(args, "--wait"))
+	assert.Assert(t, slices.Contains(args, "--retention.resolution-raw=30d"))
+	assert.Assert(t, slices.Contains(args, "--retention.resolution-1h=1y"))
+	assert.Assert(t, !slices.Contains(args, "--retention.resolution-5m="))
+}
+
+func TestObjectStorageDisabled(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NilError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NilError(t, monv1.AddToScheme(scheme))
+
+	querier := &msoapi.ThanosQuerier{
+		ObjectMeta: metav1.ObjectMeta{Name: "querier", Namespace: "ns"},
+		Spec: msoapi.ThanosQuerierSpec{
+			ObjectStorage: &msoapi.ThanosObjectStorageConfig{
+				Secret: corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "objstore"},
+					Key:                  "thanos.yaml",
+				},
+				StoreGateway: &msoapi.StoreGatewayConfig{
+					Replicas: func(i int32) *int32 { return &i }(2),
+				},
+				Compactor: &msoapi.CompactorConfig{},
+			},
+		},
+	}
+	storeName := storeGatewayName(querier)
+	compactName := compactorName(querier)
+	objects := []client.Object{
+		newStoreGatewayStatefulSet(storeName, querier, "thanos"),
+		newStoreGatewayService(storeName, querier.Namespace),
+		newServiceMonitor(storeName, querier.Namespace),
+		newPodDisruptionBudget(storeName, querier.Namespace),
+		newCompactorStatefulSet(compactName, querier, "thanos"),
+		newCompactorService(compactName, querier.Namespace),
+		newServiceMonitor(compactName, querier.Namespace),
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	// the components of a disabled object storage are deleted
+	ctx := context.Background()
+	querier.Spec.ObjectStorage = nil
+	for _, r := range objectStorageReconcilers(querier, "thanos") {
+		assert.NilError(t, r.Reconcile(ctx, c, scheme))
+	}
+	for _, obj := range objects {
+		err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		assert.Assert(t, apierrors.IsNotFound(err), "%T %s should be deleted", obj, obj.GetName())
+	}
+}
+
+func TestStoreGatewayPodDisruptionBudget(t *testing.T) {
+	querier := &msoapi.ThanosQuerier{
+		ObjectMeta: metav1.ObjectMeta{Name: "querier", Namespace: "ns"},
+		Spec: msoapi.ThanosQuerierSpec{
+			ObjectStorage: &msoapi.ThanosObjectStorageConfig{
+				StoreGateway: &msoapi.StoreGatewayConfig{},
+			},
+		},
+	}
+	updaters := func() int {
+		n := 0
+		for _, r := range objectStorageReconcilers(querier, "thanos") {
+			if _, ok := r.(reconciler.Updater); ok {
+				n++
+			}
+		}
+		return n
+	}
+
+	// a single replica has no pod disruption budget
+	assert.Equal(t, updaters(), 3)
+	querier.Spec.ObjectStorage.StoreGateway.Replicas = func(i int32) *int32 { return &i }(2)
+	assert.Equal(t, updaters(), 4)
+
+	name := storeGatewayName(querier)
+	pdb := newPodDisruptionBudget(name, querier.Namespace)
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	assert.NilError(t, err)
+	sts := newStoreGatewayStatefulSet(name, querier, "thanos")
+	assert.Assert(t, selector.Matches(labels.Set(sts.Spec.Template.Labels)))
+}
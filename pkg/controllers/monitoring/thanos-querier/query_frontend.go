@@ -0,0 +1,223 @@
+package thanos_querier
+
+import (
+	"fmt"
+
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+// queryFrontendReconcilers returns the reconcilers of the query frontend of
+// the querier, which delete the frontend when it is disabled.
+func queryFrontendReconcilers(querier *msoapi.ThanosQuerier, thanosImage string) ([]reconciler.Reconciler, error) {
+	name := "thanos-query-frontend-" + querier.Name
+	enabled := querier.Spec.QueryFrontend != nil
+
+	deployment := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: querier.Namespace,
+		},
+	}
+	if enabled {
+		var err error
+		deployment, err = newQueryFrontendDeployment(name, querier, thanosImage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return []reconciler.Reconciler{
+		reconciler.NewOptionalUpdater(deployment, querier, enabled),
+		reconciler.NewOptionalUpdater(newQueryFrontendService(name, querier.Namespace), querier, enabled),
+		reconciler.NewOptionalUpdater(newServiceMonitor(name, querier.Namespace), querier, enabled),
+		reconciler.NewOptionalUpdater(newPodDisruptionBudget(name, querier.Namespace), querier,
+			enabled && queryFrontendReplicas(querier) > 1),
+	}, nil
+}
+
+// queryFrontendReplicas returns the number of replicas of the query frontend
+// of the querier, which defaults to 1.
+func queryFrontendReplicas(querier *msoapi.ThanosQuerier) int32 {
+	if querier.Spec.QueryFrontend == nil || querier.Spec.QueryFrontend.Replicas == nil {
+		return 1
+	}
+	return *querier.Spec.QueryFrontend.Replicas
+}
+
+func newQueryFrontendDeployment(name string, querier *msoapi.ThanosQuerier, thanosImage string) (*appsv1.Deployment, error) {
+	config := querier.Spec.QueryFrontend
+	querierName := "thanos-querier-" + querier.Name
+
+	args := []string{
+		"query-frontend",
+		"--http-address=0.0.0.0:9090",
+		"--log.format=logfmt",
+		fmt.Sprintf("--log.level=%s", querierLogLevel(querier)),
+		fmt.Sprintf("--query-frontend.downstream-url=http://%s.%s.svc:%d", querierName, querier.Namespace, httpPort(querier)),
+	}
+	if config.SplitInterval != nil {
+		args = append(args, fmt.Sprintf("--query-range.split-interval=%s", *config.SplitInterval))
+	}
+	if config.MaxRetries != nil {
+		args = append(args, fmt.Sprintf("--query-range.max-retries-per-request=%d", *config.MaxRetries))
+	}
+	if config.Cache != nil {
+		cacheConfig, err := responseCacheConfig(config.Cache)
+		if err != nil {
+			return nil, err
+		}
+		if cacheConfig != "" {
+			args = append(args, fmt.Sprintf("--query-range.response-cache-config=%s", cacheConfig))
+		}
+	}
+
+	// the image of the querier overrides the image configured for the operator
+	if querier.Spec.Image != "" {
+		thanosImage = querier.Spec.Image
+	}
+
+	replicas := queryFrontendReplicas(querier)
+
+	deployment := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: querier.Namespace,
+			Labels:    componentLabels(name),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/instance": name,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: querier.Namespace,
+					Labels:    componentLabels(name),
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:      "thanos-query-frontend",
+							Args:      args,
+							Image:     thanosImage,
+							Resources: defaultResources(config.Resources),
+							Ports: []corev1.ContainerPort{
+								{
+									ContainerPort: 9090,
+									Name:          "http",
+								},
+							},
+							LivenessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "/-/healthy",
+										Port: intstr.FromString("http"),
+									},
+								},
+								PeriodSeconds:    30,
+								FailureThreshold: 4,
+							},
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "/-/ready",
+										Port: intstr.FromString("http"),
+									},
+								},
+								PeriodSeconds:    5,
+								FailureThreshold: 20,
+							},
+							TerminationMessagePolicy: "FallbackToLogsOnError",
+						},
+					},
+					NodeSelector: map[string]string{
+						"kubernetes.io/os": "linux",
+					},
+				},
+			},
+			ProgressDeadlineSeconds: func(i int32) *int32 { return &i }(300),
+		},
+	}
+
+	applyScheduling(&deployment.Spec.Template.Spec, name, replicas, querier.Spec.Scheduling)
+
+	return deployment, nil
+}
+
+// responseCacheConfig returns the response cache config of Thanos Query
+// Frontend in YAML.
+func responseCacheConfig(cache *msoapi.QueryFrontendCacheConfig) (string, error) {
+	var config map[string]interface{}
+	switch {
+	case cache.InMemory != nil:
+		inMemory := map[string]interface{}{}
+		if cache.InMemory.MaxSize != "" {
+			inMemory["max_size"] = cache.InMemory.MaxSize
+		}
+		config = map[string]interface{}{"type": "IN-MEMORY", "config": inMemory}
+	case cache.Memcached != nil:
+		config = map[string]interface{}{
+			"type":   "MEMCACHED",
+			"config": map[string]interface{}{"addresses": cache.Memcached.Addresses},
+		}
+	case cache.Redis != nil:
+		config = map[string]interface{}{
+			"type":   "REDIS",
+			"config": map[string]interface{}{"addr": cache.Redis.Address},
+		}
+	default:
+		return "", nil
+	}
+
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal response cache config: %w", err)
+	}
+	return string(out), nil
+}
+
+func newQueryFrontendService(name string, namespace string) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    componentLabels(name),
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Port:       9090,
+					Name:       "http",
+					TargetPort: intstr.FromString("http"),
+				},
+			},
+			Selector: map[string]string{
+				"app.kubernetes.io/instance": name,
+			},
+			Type: "ClusterIP",
+		},
+	}
+}
@@ -0,0 +1,92 @@
+package thanos_querier
+
+import (
+	"testing"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+	"golang.org/x/exp/slices"
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestQueryFrontend(t *testing.T) {
+	querier := &msoapi.ThanosQuerier{
+		ObjectMeta: metav1.ObjectMeta{Name: "querier", Namespace: "ns"},
+	}
+
+	reconcilers, err := queryFrontendReconcilers(querier, "thanos")
+	assert.NilError(t, err)
+	for _, r := range reconcilers {
+		_, ok := r.(reconciler.Deleter)
+		assert.Assert(t, ok)
+	}
+
+	splitInterval := monv1.Duration("12h")
+	querier.Spec.QueryFrontend = &msoapi.ThanosQueryFrontendConfig{
+		SplitInterval: &splitInterval,
+		MaxRetries:    func(i int32) *int32 { return &i }(3),
+		Cache: &msoapi.QueryFrontendCacheConfig{
+			InMemory: &msoapi.InMemoryCacheConfig{MaxSize: "256MB"},
+		},
+	}
+	reconcilers, err = queryFrontendReconcilers(querier, "thanos")
+	assert.NilError(t, err)
+	for _, r := range reconcilers[:3] {
+		_, ok := r.(reconciler.Updater)
+		assert.Assert(t, ok)
+	}
+	// a single replica isn't protected by a pod disruption budget
+	_, ok := reconcilers[3].(reconciler.Deleter)
+	assert.Assert(t, ok)
+
+	querier.Spec.QueryFrontend.Replicas = func(i int32) *int32 { return &i }(2)
+	reconcilers, err = queryFrontendReconcilers(querier, "thanos")
+	assert.NilError(t, err)
+	_, ok = reconcilers[3].(reconciler.Updater)
+	assert.Assert(t, ok)
+
+	deployment, err := newQueryFrontendDeployment("thanos-query-frontend-querier", querier, "thanos")
+	assert.NilError(t, err)
+	args := deployment.Spec.Template.Spec.Containers[0].Args
+	assert.Assert(t, slices.Contains(args, "--query-frontend.downstream-url=http://thanos-querier-querier.ns.svc:9090"))
+	assert.Assert(t, slices.Contains(args, "--query-range.split-interval=12h"))
+	assert.Assert(t, slices.Contains(args, "--query-range.max-retries-per-request=3"))
+	assert.Assert(t, slices.Contains(args, "--query-range.response-cache-config=config:\n  max_size: 256MB\ntype: IN-MEMORY\n"))
+
+	name := "thanos-query-frontend-querier"
+	assertServiceMonitorSelects(t, newServiceMonitor(name, querier.Namespace), newQueryFrontendService(name, querier.Namespace))
+}
+
+func TestResponseCacheConfig(t *testing.T) {
+	config, err := responseCacheConfig(&msoapi.QueryFrontendCacheConfig{
+		Memcached: &msoapi.MemcachedCacheConfig{Addresses: []string{"memcached-0:11211", "memcached-1:11211"}},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, config, "config:\n  addresses:\n  - memcached-0:11211\n  - memcached-1:11211\ntype: MEMCACHED\n")
+
+	config, err = responseCacheConfig(&msoapi.QueryFrontendCacheConfig{
+		Redis: &msoapi.RedisCacheConfig{Address: "redis:6379"},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, config, "config:\n  addr: redis:6379\ntype: REDIS\n")
+}
+
+func TestGrafanaDataSource(t *testing.T) {
+	querier := &msoapi.ThanosQuerier{
+		ObjectMeta: metav1.ObjectMeta{Name: "querier", Namespace: "ns"},
+	}
+	datasourceURL := func() interface{} {
+		datasources, _, err := unstructured.NestedSlice(newGrafanaDataSource(querier).Object, "spec", "datasources")
+		assert.NilError(t, err)
+		return datasources[0].(map[string]interface{})["url"]
+	}
+
+	assert.Equal(t, datasourceURL(), "http://thanos-querier-querier.ns.svc:9090")
+
+	// queries go through the query frontend when it is enabled
+	querier.Spec.QueryFrontend = &msoapi.ThanosQueryFrontendConfig{}
+	assert.Equal(t, datasourceURL(), "http://thanos-query-frontend-querier.ns.svc:9090")
+}
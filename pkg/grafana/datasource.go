@@ -0,0 +1,76 @@
+// Package grafana provides the Grafana datasources of the components managed
+// by the operator. Datasources are GrafanaDataSource resources of the Grafana
+// Operator, which are handled as unstructured objects since the API is only
+// available when the Grafana Operator is installed.
+//
+// Only the integreatly.org/v1alpha1 API of the Grafana Operator v4 is
+// supported. The GrafanaDatasource resources of the Grafana Operator v5
+// (grafana.integreatly.org/v1beta1) must select the Grafana instances which
+// import them, and the operator has no API to configure which instances
+// should get the datasources of its components.
+package grafana
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DataSourceGVK is the kind of the datasources of the Grafana Operator.
+var DataSourceGVK = schema.GroupVersionKind{Group: "integreatly.org", Version: "v1alpha1", Kind: "GrafanaDataSource"}
+
+// IsDataSourceAPIAvailable returns whether the datasource API of the Grafana
+// Operator is served by the cluster. The API is discovered again when it
+// wasn't found, so that the Grafana Operator can be installed later.
+func IsDataSourceAPIAvailable(mapper meta.RESTMapper) (bool, error) {
+	_, err := mapper.RESTMapping(DataSourceGVK.GroupKind(), DataSourceGVK.Version)
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to discover the Grafana datasource API: %w", err)
+	}
+	return true, nil
+}
+
+// NewDataSourceObject returns an empty datasource, e.g. to be watched.
+func NewDataSourceObject() *unstructured.Unstructured {
+	ds := &unstructured.Unstructured{}
+	ds.SetGroupVersionKind(DataSourceGVK)
+	return ds
+}
+
+// NewPrometheusDataSource returns a datasource of the Prometheus API served
+// at url. The datasource is named after the namespace and the name of the
+// object. caCert is the PEM encoded CA verifying the certificate of an HTTPS
+// url, if any.
+func NewPrometheusDataSource(name string, namespace string, labels map[string]string, url string, caCert []byte) *unstructured.Unstructured {
+	datasource := map[string]interface{}{
+		"name":      fmt.Sprintf("%s/%s", namespace, name),
+		"type":      "prometheus",
+		"access":    "proxy",
+		"url":       url,
+		"isDefault": false,
+		"editable":  false,
+	}
+	if len(caCert) > 0 {
+		datasource["jsonData"] = map[string]interface{}{
+			"tlsAuthWithCACert": true,
+		}
+		datasource["secureJsonData"] = map[string]interface{}{
+			"tlsCACert": string(caCert),
+		}
+	}
+
+	ds := NewDataSourceObject()
+	ds.SetName(name)
+	ds.SetNamespace(namespace)
+	ds.SetLabels(labels)
+	ds.Object["spec"] = map[string]interface{}{
+		"name":        fmt.Sprintf("%s-%s.yaml", namespace, name),
+		"datasources": []interface{}{datasource},
+	}
+	return ds
+}
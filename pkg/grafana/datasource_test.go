@@ -0,0 +1,47 @@
+package grafana
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestNewPrometheusDataSource(t *testing.T) {
+	ds := NewPrometheusDataSource("stack-prometheus", "ns", nil, "http://stack-prometheus.ns.svc:9090", nil)
+	assert.Equal(t, ds.GetObjectKind().GroupVersionKind(), DataSourceGVK)
+	assert.Equal(t, ds.GetName(), "stack-prometheus")
+	assert.Equal(t, ds.GetNamespace(), "ns")
+
+	name, _, err := unstructured.NestedString(ds.Object, "spec", "name")
+	assert.NilError(t, err)
+	assert.Equal(t, name, "ns-stack-prometheus.yaml")
+
+	datasources, _, err := unstructured.NestedSlice(ds.Object, "spec", "datasources")
+	assert.NilError(t, err)
+	assert.Equal(t, len(datasources), 1)
+	datasource := datasources[0].(map[string]interface{})
+	assert.Equal(t, datasource["name"], "ns/stack-prometheus")
+	assert.Equal(t, datasource["url"], "http://stack-prometheus.ns.svc:9090")
+	assert.Assert(t, datasource["secureJsonData"] == nil)
+
+	ds = NewPrometheusDataSource("stack-prometheus", "ns", nil, "https://stack-prometheus.ns.svc:9090", []byte("ca"))
+	datasources, _, err = unstructured.NestedSlice(ds.Object, "spec", "datasources")
+	assert.NilError(t, err)
+	caCert, _, err := unstructured.NestedString(datasources[0].(map[string]interface{}), "secureJsonData", "tlsCACert")
+	assert.NilError(t, err)
+	assert.Equal(t, caCert, "ca")
+}
+
+func TestIsDataSourceAPIAvailable(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	available, err := IsDataSourceAPIAvailable(mapper)
+	assert.NilError(t, err)
+	assert.Assert(t, !available)
+
+	mapper.Add(DataSourceGVK, meta.RESTScopeNamespace)
+	available, err = IsDataSourceAPIAvailable(mapper)
+	assert.NilError(t, err)
+	assert.Assert(t, available)
+}
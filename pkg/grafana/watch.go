@@ -0,0 +1,80 @@
+package grafana
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// discoveryInterval is the minimum interval between two discoveries of the
+// datasource API while it isn't available. Every discovery of a missing API
+// makes the REST mapper reload the discovery information of the cluster.
+const discoveryInterval = time.Minute
+
+// DataSourceWatcher makes a controller watch the datasources owned by its
+// objects once the Grafana Operator is installed. The Grafana Operator may be
+// installed at any time, so its API is discovered again until it is
+// available.
+type DataSourceWatcher struct {
+	mapper     meta.RESTMapper
+	controller controller.Controller
+	owner      client.Object
+	logger     logr.Logger
+	now        func() time.Time
+
+	mu            sync.Mutex
+	watching      bool
+	nextDiscovery time.Time
+}
+
+// NewDataSourceWatcher returns a watcher of the datasources owned by objects
+// of the type of owner, which are reconciled by c.
+func NewDataSourceWatcher(mapper meta.RESTMapper, c controller.Controller, owner client.Object, logger logr.Logger) *DataSourceWatcher {
+	return &DataSourceWatcher{
+		mapper:     mapper,
+		controller: c,
+		owner:      owner,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// EnsureWatch starts watching the datasources when the datasource API is
+// available and returns whether it is. The API is discovered at most once
+// per discovery interval while it isn't available.
+func (w *DataSourceWatcher) EnsureWatch() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watching {
+		return true, nil
+	}
+	if w.now().Before(w.nextDiscovery) {
+		return false, nil
+	}
+
+	available, err := IsDataSourceAPIAvailable(w.mapper)
+	if err != nil || !available {
+		w.nextDiscovery = w.now().Add(discoveryInterval)
+		return false, err
+	}
+
+	err = w.controller.Watch(
+		&source.Kind{Type: NewDataSourceObject()},
+		&handler.EnqueueRequestForOwner{OwnerType: w.owner, IsController: true},
+		predicate.GenerationChangedPredicate{},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to watch Grafana datasources: %w", err)
+	}
+	w.logger.Info("Grafana Operator detected, managing Grafana datasources")
+	w.watching = true
+	return true, nil
+}
@@ -0,0 +1,56 @@
+package grafana
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+type fakeController struct {
+	controller.Controller
+	watches int
+}
+
+func (c *fakeController) Watch(source.Source, handler.EventHandler, ...predicate.Predicate) error {
+	c.watches++
+	return nil
+}
+
+func TestDataSourceWatcher(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	c := &fakeController{}
+	w := NewDataSourceWatcher(mapper, c, &corev1.ConfigMap{}, logr.Discard())
+	now := time.Now()
+	w.now = func() time.Time { return now }
+
+	// no watch is registered while the Grafana Operator isn't installed
+	watching, err := w.EnsureWatch()
+	assert.NilError(t, err)
+	assert.Assert(t, !watching)
+	assert.Equal(t, c.watches, 0)
+
+	// the missing API isn't discovered again before the discovery interval
+	mapper.Add(DataSourceGVK, meta.RESTScopeNamespace)
+	watching, err = w.EnsureWatch()
+	assert.NilError(t, err)
+	assert.Assert(t, !watching)
+
+	now = now.Add(discoveryInterval)
+	watching, err = w.EnsureWatch()
+	assert.NilError(t, err)
+	assert.Assert(t, watching)
+
+	// the datasources are watched once
+	watching, err = w.EnsureWatch()
+	assert.NilError(t, err)
+	assert.Assert(t, watching)
+	assert.Equal(t, c.watches, 1)
+}
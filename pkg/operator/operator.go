@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/rhobs/observability-operator/pkg/certs"
 	stackctrl "github.com/rhobs/observability-operator/pkg/controllers/monitoring/monitoring-stack"
 	tqctrl "github.com/rhobs/observability-operator/pkg/controllers/monitoring/thanos-querier"
+	"github.com/rhobs/observability-operator/pkg/webhooks"
 
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 
@@ -20,39 +23,118 @@ const instanceSelector = "app.kubernetes.io/managed-by=observability-operator"
 
 const ObservabilityOperatorName = "observability-operator"
 
+// DefaultThanosImage is the Thanos image deployed when no image is configured
+const DefaultThanosImage = "quay.io/thanos/thanos:v0.24.0"
+
+// DefaultWebhookCertDir is the directory in which OLM mounts the serving
+// certificate of the webhook server
+const DefaultWebhookCertDir = "/tmp/k8s-webhook-server/serving-certs"
+
 // Operator embedds manager and exposes only the minimal set of functions
 type Operator struct {
 	manager manager.Manager
+	// certProvisioner is nil when the serving certificate of the webhook
+	// server is provided by the environment
+	certProvisioner *webhooks.CertificateProvisioner
+}
+
+// OperatorConfiguration holds the configuration of the operator
+type OperatorConfiguration struct {
+	// Namespace is the namespace in which the operator runs
+	Namespace       string
+	MetricsAddr     string
+	HealthProbeAddr string
+	// PrometheusImage is the image of Prometheus. The default image of
+	// Prometheus Operator is used when empty.
+	PrometheusImage string
+	// AlertmanagerImage is the image of Alertmanager. The default image of
+	// Prometheus Operator is used when empty.
+	AlertmanagerImage string
+	// ThanosImage is the image of the Thanos sidecar and Thanos Querier
+	// unless overridden by a MonitoringStack or ThanosQuerier.
+	ThanosImage string
+	// WebhookCertDir is the directory holding the serving certificate of
+	// the webhook server. The operator provisions the certificate when the
+	// directory doesn't contain one.
+	WebhookCertDir string
 }
 
-func New(metricsAddr, healthProbeAddr string) (*Operator, error) {
+func New(cfg *OperatorConfiguration) (*Operator, error) {
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 NewScheme(),
-		MetricsBindAddress:     metricsAddr,
-		HealthProbeBindAddress: healthProbeAddr,
+		MetricsBindAddress:     cfg.MetricsAddr,
+		HealthProbeBindAddress: cfg.HealthProbeAddr,
+		// Secrets are only read to check the certificates managed by the
+		// operator, which doesn't justify caching all secrets of the cluster.
+		ClientDisableCacheFor: []client.Object{&corev1.Secret{}},
+		CertDir:               cfg.WebhookCertDir,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("unable to create manager: %w", err)
 	}
 
-	if err := stackctrl.RegisterWithManager(mgr, stackctrl.Options{InstanceSelector: instanceSelector}); err != nil {
+	thanosImage := cfg.ThanosImage
+	if thanosImage == "" {
+		thanosImage = DefaultThanosImage
+	}
+
+	caProvider := certs.NewCAProvider(mgr.GetClient(), cfg.Namespace)
+
+	if err := stackctrl.RegisterWithManager(mgr, stackctrl.Options{
+		InstanceSelector:  instanceSelector,
+		PrometheusImage:   cfg.PrometheusImage,
+		AlertmanagerImage: cfg.AlertmanagerImage,
+		ThanosImage:       thanosImage,
+		CAProvider:        caProvider,
+	}); err != nil {
 		return nil, fmt.Errorf("unable to register monitoring stack controller: %w", err)
 	}
 
-	if err := tqctrl.RegisterWithManager(mgr); err != nil {
+	if err := tqctrl.RegisterWithManager(mgr, tqctrl.Options{
+		ThanosImage: thanosImage,
+		CAProvider:  caProvider,
+	}); err != nil {
 		return nil, fmt.Errorf("unable to register the thanos querier controller with the manager: %w", err)
 	}
 
+	if err := webhooks.RegisterWithManager(mgr); err != nil {
+		return nil, fmt.Errorf("unable to register webhooks: %w", err)
+	}
+
+	// The webhook server needs its certificate before the manager and its
+	// cache are started, so the certificate is provisioned with a client
+	// reading directly from the API server.
+	directClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create client: %w", err)
+	}
+	var certProvisioner *webhooks.CertificateProvisioner
+	provisioner := webhooks.NewCertificateProvisioner(directClient, certs.NewCAProvider(directClient, cfg.Namespace),
+		cfg.Namespace, cfg.WebhookCertDir, ctrl.Log.WithName("webhook-certificate"))
+	if !provisioner.IsProvided() {
+		certProvisioner = provisioner
+		if err := mgr.Add(certProvisioner); err != nil {
+			return nil, fmt.Errorf("unable to add webhook certificate provisioner: %w", err)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("health probe", healthz.Ping); err != nil {
 		return nil, fmt.Errorf("unable to add health probe: %w", err)
 	}
 
 	return &Operator{
-		manager: mgr,
+		manager:         mgr,
+		certProvisioner: certProvisioner,
 	}, nil
 }
 
 func (o *Operator) Start(ctx context.Context) error {
+	if o.certProvisioner != nil {
+		if err := o.certProvisioner.Provision(ctx); err != nil {
+			return fmt.Errorf("unable to provision webhook certificate: %w", err)
+		}
+	}
+
 	if err := o.manager.Start(ctx); err != nil {
 		return fmt.Errorf("unable to start manager: %w", err)
 	}
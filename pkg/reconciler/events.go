@@ -0,0 +1,93 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Reasons of the events recorded by the EventRecorder.
+const (
+	CreatedReason         = "Created"
+	DeletedReason         = "Deleted"
+	ApplyFailedReason     = "ApplyFailed"
+	DeleteFailedReason    = "DeleteFailed"
+	ReconcileFailedReason = "ReconcileFailed"
+)
+
+// EventRecorder runs reconcilers and records events on the owner of their
+// resources when the resources are created or deleted, or when reconciling
+// them fails. Errors which are retried, caused by a stale cache, aren't
+// recorded.
+type EventRecorder struct {
+	recorder record.EventRecorder
+}
+
+// NewEventRecorder returns an EventRecorder recording events with recorder.
+func NewEventRecorder(recorder record.EventRecorder) EventRecorder {
+	return EventRecorder{recorder: recorder}
+}
+
+// Reconcile runs the reconciler r and records events on owner.
+func (e EventRecorder) Reconcile(ctx context.Context, c client.Client, scheme *runtime.Scheme, owner runtime.Object, r Reconciler) error {
+	switch r := r.(type) {
+	case Updater:
+		start := time.Now()
+		if err := r.Reconcile(ctx, c, scheme); err != nil {
+			e.recordFailure(owner, ApplyFailedReason, err)
+			return err
+		}
+		if createdSince(r.resource, start) {
+			e.recorder.Eventf(owner, corev1.EventTypeNormal, CreatedReason, "Created %s", describe(r.resource))
+		}
+		return nil
+
+	case Deleter:
+		deleted, err := r.delete(ctx, c)
+		if err != nil {
+			e.recordFailure(owner, DeleteFailedReason, err)
+			return err
+		}
+		if deleted {
+			e.recorder.Eventf(owner, corev1.EventTypeNormal, DeletedReason, "Deleted %s", describe(r.resource))
+		}
+		return nil
+
+	default:
+		err := r.Reconcile(ctx, c, scheme)
+		if err != nil {
+			e.recordFailure(owner, ReconcileFailedReason, err)
+		}
+		return err
+	}
+}
+
+// createdSince returns whether the applied resource, as returned by the API
+// server, was created since start. Creation timestamps are truncated to
+// seconds, and so is start. This avoids reading the resource before applying it,
+// which would require watching every kind of applied resource.
+func createdSince(resource client.Object, start time.Time) bool {
+	created := resource.GetCreationTimestamp()
+	return !created.IsZero() && !created.Time.Before(start.Truncate(time.Second))
+}
+
+func (e EventRecorder) recordFailure(owner runtime.Object, reason string, err error) {
+	if apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err) {
+		return
+	}
+	e.recorder.Event(owner, corev1.EventTypeWarning, reason, err.Error())
+}
+
+func describe(resource client.Object) string {
+	kind := resource.GetObjectKind().GroupVersionKind().Kind
+	if resource.GetNamespace() == "" {
+		return fmt.Sprintf("%s %s", kind, resource.GetName())
+	}
+	return fmt.Sprintf("%s %s/%s", kind, resource.GetNamespace(), resource.GetName())
+}
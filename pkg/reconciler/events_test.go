@@ -0,0 +1,101 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type failingReconciler struct{}
+
+func (failingReconciler) Reconcile(ctx context.Context, c client.Client, scheme *runtime.Scheme) error {
+	return errors.New("failed")
+}
+
+// applyClient creates the resources which are applied but don't exist, since
+// the fake client doesn't support server-side apply. Created resources get a
+// creation timestamp, as they do from the API server.
+type applyClient struct {
+	client.Client
+}
+
+func (c applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	if apierrors.IsNotFound(err) {
+		obj.SetCreationTimestamp(metav1.Now())
+		return c.Client.Create(ctx, obj)
+	}
+	return err
+}
+
+// forbiddenClient fails to read resources, as a client without the permission
+// to get them does.
+type forbiddenClient struct {
+	applyClient
+}
+
+func (c forbiddenClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return apierrors.NewForbidden(corev1.Resource("configmaps"), key.Name, errors.New("forbidden"))
+}
+
+func newConfigMap(name string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+	}
+}
+
+func TestEventRecorder(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NilError(t, clientgoscheme.AddToScheme(scheme))
+	owner := newConfigMap("owner")
+	c := applyClient{fake.NewClientBuilder().WithScheme(scheme).WithObjects(owner, newConfigMap("existing")).Build()}
+	recorder := record.NewFakeRecorder(10)
+	e := NewEventRecorder(recorder)
+	ctx := context.Background()
+
+	assert.NilError(t, e.Reconcile(ctx, c, scheme, owner, NewUpdater(newConfigMap("created"), owner)))
+	assert.Equal(t, <-recorder.Events, "Normal Created Created ConfigMap ns/created")
+
+	// updating an existing resource and deleting a missing one are not recorded
+	assert.NilError(t, e.Reconcile(ctx, c, scheme, owner, NewUpdater(newConfigMap("existing"), owner)))
+	assert.NilError(t, e.Reconcile(ctx, c, scheme, owner, NewDeleter(newConfigMap("missing"))))
+
+	assert.NilError(t, e.Reconcile(ctx, c, scheme, owner, NewDeleter(newConfigMap("existing"))))
+	assert.Equal(t, <-recorder.Events, "Normal Deleted Deleted ConfigMap ns/existing")
+
+	assert.Error(t, e.Reconcile(ctx, c, scheme, owner, failingReconciler{}), "failed")
+	assert.Equal(t, <-recorder.Events, "Warning ReconcileFailed failed")
+
+	assert.Equal(t, len(recorder.Events), 0)
+}
+
+func TestEventRecorderWithoutRead(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NilError(t, clientgoscheme.AddToScheme(scheme))
+	owner := newConfigMap("owner")
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(owner).Build()
+	c := forbiddenClient{applyClient{fakeClient}}
+	recorder := record.NewFakeRecorder(10)
+	e := NewEventRecorder(recorder)
+	ctx := context.Background()
+
+	// the creation is detected from the applied resource, which isn't read
+	assert.NilError(t, e.Reconcile(ctx, c, scheme, owner, NewUpdater(newConfigMap("created"), owner)))
+	assert.NilError(t, fakeClient.Get(ctx, client.ObjectKey{Name: "created", Namespace: "ns"}, &corev1.ConfigMap{}))
+	assert.Equal(t, <-recorder.Events, "Normal Created Created ConfigMap ns/created")
+	assert.Equal(t, len(recorder.Events), 0)
+}
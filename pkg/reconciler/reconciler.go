@@ -4,12 +4,16 @@ import (
 	"context"
 	"fmt"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+// FieldManager is the field manager of the resources applied by the Updater.
+const FieldManager = "observability-operator"
+
 // This interface is used by the resourceManagers to reconicle the resources they
 // watch. If any component needs special treatment in the reconcile loop, create
 // a new type that implements this interface.
@@ -34,7 +38,7 @@ func (r Updater) Reconcile(ctx context.Context, c client.Client, scheme *runtime
 		}
 	}
 
-	if err := c.Patch(ctx, r.resource, client.Apply, client.ForceOwnership, client.FieldOwner("observability-operator")); err != nil {
+	if err := c.Patch(ctx, r.resource, client.Apply, client.ForceOwnership, client.FieldOwner(FieldManager)); err != nil {
 		return fmt.Errorf("%s/%s (%s): updater failed to patch: %w",
 			r.resource.GetNamespace(), r.resource.GetName(),
 			r.resource.GetObjectKind().GroupVersionKind().String(), err)
@@ -55,12 +59,22 @@ type Deleter struct {
 }
 
 func (r Deleter) Reconcile(ctx context.Context, c client.Client, scheme *runtime.Scheme) error {
-	if err := c.Delete(ctx, r.resource); client.IgnoreNotFound(err) != nil {
-		return fmt.Errorf("%s/%s (%s): deleter failed to delete: %w",
+	_, err := r.delete(ctx, c)
+	return err
+}
+
+// delete deletes the resource and returns whether it existed.
+func (r Deleter) delete(ctx context.Context, c client.Client) (bool, error) {
+	err := c.Delete(ctx, r.resource)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s/%s (%s): deleter failed to delete: %w",
 			r.resource.GetNamespace(), r.resource.GetName(),
 			r.resource.GetObjectKind().GroupVersionKind().String(), err)
 	}
-	return nil
+	return true, nil
 }
 
 func NewDeleter(r client.Object) Deleter {
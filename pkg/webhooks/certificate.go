@@ -0,0 +1,217 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/rhobs/observability-operator/pkg/certs"
+
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ServiceName is the name of the service through which the API server
+	// reaches the webhooks. It is also the name of the webhook configurations.
+	ServiceName = "observability-operator"
+
+	// renewalInterval is the interval at which the provisioned serving
+	// certificate is checked for renewal.
+	renewalInterval = time.Hour
+
+	// provisionedMarker is the file marking the certificate directory as
+	// provisioned by the operator. Certificates found in the directory without
+	// the marker are provided by the environment and are never replaced.
+	provisionedMarker = ".provisioned-by-observability-operator"
+)
+
+// conversionCRDs are the CRDs served by the conversion webhook
+var conversionCRDs = []string{
+	"monitoringstacks.monitoring.rhobs",
+	"thanosqueriers.monitoring.rhobs",
+}
+
+// CertificateProvisioner provisions the serving certificate of the webhook
+// server when it isn't provided by the environment. OLM mounts a certificate
+// into the certificate directory and injects its CA into the webhook
+// configurations it creates. Other installs, e.g. through package-operator,
+// rely on the operator to sign a certificate with its own CA and to inject
+// the CA into the webhook configurations and the CRDs.
+type CertificateProvisioner struct {
+	client     client.Client
+	caProvider *certs.CAProvider
+	namespace  string
+	certDir    string
+	logger     logr.Logger
+}
+
+// NewCertificateProvisioner returns a CertificateProvisioner writing the
+// certificate into certDir. c must not depend on the cache of the manager
+// since the certificate is needed before the manager is started.
+func NewCertificateProvisioner(c client.Client, caProvider *certs.CAProvider, namespace string, certDir string, logger logr.Logger) *CertificateProvisioner {
+	return &CertificateProvisioner{
+		client:     c,
+		caProvider: caProvider,
+		namespace:  namespace,
+		certDir:    certDir,
+		logger:     logger,
+	}
+}
+
+// IsProvided returns true when the certificate directory already holds a
+// certificate which isn't managed by the operator. Certificates provisioned by
+// the operator, even partially written ones, carry the provisioned marker and
+// keep being renewed by the operator.
+func (p *CertificateProvisioner) IsProvided() bool {
+	if _, err := os.Stat(filepath.Join(p.certDir, provisionedMarker)); err == nil {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(p.certDir, certs.CertificateKey))
+	return err == nil
+}
+
+// Provision writes a valid serving certificate into the certificate directory
+// and injects the CA into the webhook configurations and CRDs.
+func (p *CertificateProvisioner) Provision(ctx context.Context) error {
+	ca, err := p.caProvider.CA(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	certPath := filepath.Join(p.certDir, certs.CertificateKey)
+	keyPath := filepath.Join(p.certDir, certs.PrivateKeyKey)
+	certPEM, _ := os.ReadFile(certPath)
+	keyPEM, _ := os.ReadFile(keyPath)
+	if !ca.IsValidCertificate(certPEM, keyPEM, p.dnsNames(), now) {
+		certPEM, keyPEM, err = ca.NewCertificate(ServiceName, p.dnsNames(), now)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(p.certDir, 0o700); err != nil {
+			return fmt.Errorf("failed to create certificate directory: %w", err)
+		}
+		// the marker is written first so that a partially written certificate
+		// is still recognized as provisioned by the operator
+		if err := os.WriteFile(filepath.Join(p.certDir, provisionedMarker), []byte(ServiceName), 0o600); err != nil {
+			return fmt.Errorf("failed to mark webhook certificate: %w", err)
+		}
+		// the key is written first so that the certificate watcher of the
+		// webhook server never loads a new certificate with the old key
+		if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+			return fmt.Errorf("failed to write webhook key: %w", err)
+		}
+		if err := os.WriteFile(certPath, certPEM, 0o600); err != nil {
+			return fmt.Errorf("failed to write webhook certificate: %w", err)
+		}
+		p.logger.Info("provisioned webhook certificate", "dir", p.certDir)
+	}
+
+	return p.injectCABundle(ctx, ca.CertPEM())
+}
+
+// Start renews the certificate until ctx is done. It implements the Runnable
+// interface of the manager.
+func (p *CertificateProvisioner) Start(ctx context.Context) error {
+	ticker := time.NewTicker(renewalInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := p.Provision(ctx); err != nil {
+				p.logger.Error(err, "failed to renew webhook certificate")
+			}
+		}
+	}
+}
+
+func (p *CertificateProvisioner) dnsNames() []string {
+	svc := ServiceName + "." + p.namespace + ".svc"
+	return []string{svc, svc + ".cluster.local"}
+}
+
+// injectCABundle sets the CA bundle of the webhook configurations of the
+// operator and of the conversion webhooks of its CRDs. Missing objects are
+// ignored since the webhooks are optional.
+func (p *CertificateProvisioner) injectCABundle(ctx context.Context, caBundle []byte) error {
+	mutating := &admissionv1.MutatingWebhookConfiguration{}
+	if found, err := p.get(ctx, ServiceName, mutating); err != nil {
+		return err
+	} else if found {
+		changed := false
+		for i := range mutating.Webhooks {
+			changed = setCABundle(&mutating.Webhooks[i].ClientConfig.CABundle, caBundle) || changed
+		}
+		if err := p.update(ctx, mutating, changed); err != nil {
+			return err
+		}
+	}
+
+	validating := &admissionv1.ValidatingWebhookConfiguration{}
+	if found, err := p.get(ctx, ServiceName, validating); err != nil {
+		return err
+	} else if found {
+		changed := false
+		for i := range validating.Webhooks {
+			changed = setCABundle(&validating.Webhooks[i].ClientConfig.CABundle, caBundle) || changed
+		}
+		if err := p.update(ctx, validating, changed); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range conversionCRDs {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		found, err := p.get(ctx, name, crd)
+		if err != nil {
+			return err
+		}
+		conversion := crd.Spec.Conversion
+		if !found || conversion == nil || conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil {
+			continue
+		}
+		changed := setCABundle(&conversion.Webhook.ClientConfig.CABundle, caBundle)
+		if err := p.update(ctx, crd, changed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setCABundle(dst *[]byte, caBundle []byte) bool {
+	if bytes.Equal(*dst, caBundle) {
+		return false
+	}
+	*dst = caBundle
+	return true
+}
+
+func (p *CertificateProvisioner) get(ctx context.Context, name string, obj client.Object) (bool, error) {
+	err := p.client.Get(ctx, client.ObjectKey{Name: name}, obj)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get %T %s: %w", obj, name, err)
+	}
+	return true, nil
+}
+
+func (p *CertificateProvisioner) update(ctx context.Context, obj client.Object, changed bool) error {
+	if !changed {
+		return nil
+	}
+	if err := p.client.Update(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to inject CA bundle into %T %s: %w", obj, obj.GetName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package webhooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/rhobs/observability-operator/pkg/certs"
+
+	"gotest.tools/v3/assert"
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCertificateProvisioner(t *testing.T) {
+	ctx := context.Background()
+	validating := &admissionv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: ServiceName},
+		Webhooks:   []admissionv1.ValidatingWebhook{{Name: "a"}, {Name: "b"}},
+	}
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "monitoringstacks.monitoring.rhobs"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Conversion: &apiextensionsv1.CustomResourceConversion{
+				Strategy: apiextensionsv1.WebhookConverter,
+				Webhook: &apiextensionsv1.WebhookConversion{
+					ClientConfig: &apiextensionsv1.WebhookClientConfig{},
+				},
+			},
+		},
+	}
+	scheme := runtime.NewScheme()
+	assert.NilError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NilError(t, apiextensionsv1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(validating, crd).Build()
+	certDir := filepath.Join(t.TempDir(), "certs")
+
+	p := NewCertificateProvisioner(c, certs.NewCAProvider(c, "ns"), "ns", certDir, logr.Discard())
+	assert.Assert(t, !p.IsProvided())
+	assert.NilError(t, p.Provision(ctx))
+	// the provisioned certificate is still managed by the operator
+	assert.Assert(t, !p.IsProvided())
+
+	ca, err := certs.NewCAProvider(c, "ns").CA(ctx)
+	assert.NilError(t, err)
+	certPEM, err := os.ReadFile(filepath.Join(certDir, certs.CertificateKey))
+	assert.NilError(t, err)
+	keyPEM, err := os.ReadFile(filepath.Join(certDir, certs.PrivateKeyKey))
+	assert.NilError(t, err)
+	assert.Assert(t, ca.IsValidCertificate(certPEM, keyPEM,
+		[]string{"observability-operator.ns.svc", "observability-operator.ns.svc.cluster.local"}, time.Now()))
+
+	// the CA is injected although the mutating webhook configuration and
+	// one of the CRDs don't exist
+	assert.NilError(t, c.Get(ctx, client.ObjectKeyFromObject(validating), validating))
+	for _, w := range validating.Webhooks {
+		assert.DeepEqual(t, w.ClientConfig.CABundle, ca.CertPEM())
+	}
+	assert.NilError(t, c.Get(ctx, client.ObjectKeyFromObject(crd), crd))
+	assert.DeepEqual(t, crd.Spec.Conversion.Webhook.ClientConfig.CABundle, ca.CertPEM())
+
+	// a valid certificate is kept
+	assert.NilError(t, p.Provision(ctx))
+	renewed, err := os.ReadFile(filepath.Join(certDir, certs.CertificateKey))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, renewed, certPEM)
+}
+
+func TestCertificateProvisionerIsProvided(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NilError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NilError(t, apiextensionsv1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	certDir := t.TempDir()
+	p := NewCertificateProvisioner(c, certs.NewCAProvider(c, "ns"), "ns", certDir, logr.Discard())
+
+	// a certificate written by the environment is provided
+	certPath := filepath.Join(certDir, certs.CertificateKey)
+	assert.NilError(t, os.WriteFile(certPath, []byte("provided"), 0o600))
+	assert.Assert(t, p.IsProvided())
+
+	// a partially written certificate of the operator is renewed
+	assert.NilError(t, os.WriteFile(filepath.Join(certDir, provisionedMarker), []byte(ServiceName), 0o600))
+	assert.Assert(t, !p.IsProvided())
+	assert.NilError(t, p.Provision(context.Background()))
+	keyPEM, err := os.ReadFile(filepath.Join(certDir, certs.PrivateKeyKey))
+	assert.NilError(t, err)
+	certPEM, err := os.ReadFile(certPath)
+	assert.NilError(t, err)
+	ca, err := certs.NewCAProvider(c, "ns").CA(context.Background())
+	assert.NilError(t, err)
+	assert.Assert(t, ca.IsValidCertificate(certPEM, keyPEM, p.dnsNames(), time.Now()))
+}
@@ -0,0 +1,273 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	"github.com/prometheus/common/model"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+)
+
+// Defaults of the MonitoringStack API. They must match the defaults declared
+// by the kubebuilder markers of the API types.
+const (
+	defaultLogLevel  = stack.Info
+	defaultRetention = "120h"
+	defaultReplicas  = 2
+	defaultPath      = "/"
+)
+
+type monitoringStackDefaulter struct{}
+
+func (d *monitoringStackDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	ms, ok := obj.(*stack.MonitoringStack)
+	if !ok {
+		return fmt.Errorf("expected a MonitoringStack but got %T", obj)
+	}
+	defaultMonitoringStack(ms)
+	return nil
+}
+
+// defaultMonitoringStack applies the defaults of the CRD to the unset fields
+// of ms, so that a stack doesn't depend on whether the webhook was called.
+// Resources aren't defaulted: the API server defaults them before calling the
+// webhook, hence empty resources are set explicitly.
+func defaultMonitoringStack(ms *stack.MonitoringStack) {
+	spec := &ms.Spec
+	if spec.LogLevel == "" {
+		spec.LogLevel = defaultLogLevel
+	}
+	if spec.Retention == "" {
+		spec.Retention = defaultRetention
+	}
+
+	if spec.PrometheusConfig == nil {
+		spec.PrometheusConfig = &stack.PrometheusConfig{}
+	}
+	if spec.PrometheusConfig.Replicas == nil {
+		spec.PrometheusConfig.Replicas = pointer.Int32(defaultReplicas)
+	}
+	defaultScheduling(spec.PrometheusConfig.Scheduling)
+
+	am := &spec.AlertmanagerConfig
+	if am.Replicas == nil {
+		am.Replicas = pointer.Int32(defaultReplicas)
+	}
+	if am.Retention == "" {
+		am.Retention = defaultRetention
+	}
+	defaultScheduling(am.Scheduling)
+
+	if spec.Web != nil && spec.Web.Expose != nil {
+		defaultExposedEndpoint(spec.Web.Expose.Prometheus)
+		defaultExposedEndpoint(spec.Web.Expose.Alertmanager)
+	}
+}
+
+func defaultScheduling(scheduling *stack.SchedulingConfig) {
+	if scheduling != nil && scheduling.PodAntiAffinity == "" {
+		scheduling.PodAntiAffinity = stack.RequiredPodAntiAffinity
+	}
+}
+
+func defaultExposedEndpoint(endpoint *stack.ExposedEndpoint) {
+	if endpoint != nil && endpoint.Path == "" {
+		endpoint.Path = defaultPath
+	}
+}
+
+type monitoringStackValidator struct{}
+
+func (v *monitoringStackValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return v.validate(obj)
+}
+
+// ValidateUpdate only validates changes of the spec, so that the metadata of
+// MonitoringStacks which were accepted by earlier rules can still be updated,
+// e.g. to remove their finalizers.
+func (v *monitoringStackValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	old, ok := oldObj.(*stack.MonitoringStack)
+	if !ok {
+		return fmt.Errorf("expected a MonitoringStack but got %T", oldObj)
+	}
+	ms, ok := newObj.(*stack.MonitoringStack)
+	if !ok {
+		return fmt.Errorf("expected a MonitoringStack but got %T", newObj)
+	}
+	if !ms.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(old.Spec, ms.Spec) {
+		return nil
+	}
+	return v.validate(newObj)
+}
+
+func (v *monitoringStackValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func (v *monitoringStackValidator) validate(obj runtime.Object) error {
+	ms, ok := obj.(*stack.MonitoringStack)
+	if !ok {
+		return fmt.Errorf("expected a MonitoringStack but got %T", obj)
+	}
+	if errs := validateMonitoringStack(ms); len(errs) > 0 {
+		return apierrors.NewInvalid(stack.GroupVersion.WithKind("MonitoringStack").GroupKind(), ms.Name, errs)
+	}
+	return nil
+}
+
+func validateMonitoringStack(ms *stack.MonitoringStack) field.ErrorList {
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	opts := metav1validation.LabelSelectorValidationOptions{}
+	if ms.Spec.ResourceSelector != nil {
+		errs = append(errs, metav1validation.ValidateLabelSelector(ms.Spec.ResourceSelector, opts, specPath.Child("resourceSelector"))...)
+	}
+	if ms.Spec.NamespaceSelector != nil {
+		errs = append(errs, metav1validation.ValidateLabelSelector(ms.Spec.NamespaceSelector, opts, specPath.Child("namespaceSelector"))...)
+	}
+
+	errs = append(errs, validatePrometheusRetention(ms.Spec.Retention, specPath.Child("retention"))...)
+
+	if pc := ms.Spec.PrometheusConfig; pc != nil {
+		pcPath := specPath.Child("prometheusConfig")
+		errs = append(errs, validatePersistentVolumeClaim(pc.PersistentVolumeClaim, pcPath.Child("persistentVolumeClaim"))...)
+		errs = append(errs, validateRemoteWrite(pc.RemoteWrite, pcPath.Child("remoteWrite"))...)
+	}
+
+	amPath := specPath.Child("alertmanagerConfig")
+	errs = append(errs, validateAlertmanagerRetention(ms.Spec.AlertmanagerConfig.Retention, amPath.Child("retention"))...)
+	errs = append(errs, validatePersistentVolumeClaim(ms.Spec.AlertmanagerConfig.PersistentVolumeClaim, amPath.Child("persistentVolumeClaim"))...)
+
+	if os := ms.Spec.ObjectStorage; os != nil {
+		secretPath := specPath.Child("objectStorage", "secret")
+		if os.Secret.Name == "" {
+			errs = append(errs, field.Required(secretPath.Child("name"), "the name of the secret is required"))
+		}
+		if os.Secret.Key == "" {
+			errs = append(errs, field.Required(secretPath.Child("key"), "the key of the object storage config is required"))
+		}
+	}
+
+	return errs
+}
+
+// validatePrometheusRetention rejects retentions which Prometheus can't parse
+// or which would delete all data right away. An empty retention is defaulted.
+func validatePrometheusRetention(retention monv1.Duration, fldPath *field.Path) field.ErrorList {
+	if retention == "" {
+		return nil
+	}
+	d, err := model.ParseDuration(string(retention))
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, retention, err.Error())}
+	}
+	if d <= 0 {
+		return field.ErrorList{field.Invalid(fldPath, retention, "must be greater than 0")}
+	}
+	return nil
+}
+
+func validateAlertmanagerRetention(retention monv1.GoDuration, fldPath *field.Path) field.ErrorList {
+	if retention == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(string(retention))
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, retention, err.Error())}
+	}
+	if d <= 0 {
+		return field.ErrorList{field.Invalid(fldPath, retention, "must be greater than 0")}
+	}
+	return nil
+}
+
+// validatePersistentVolumeClaim rejects claims which can't hold any data
+// since the volumes would never be provisioned.
+func validatePersistentVolumeClaim(pvc *corev1.PersistentVolumeClaimSpec, fldPath *field.Path) field.ErrorList {
+	if pvc == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	requestPath := fldPath.Child("resources", "requests").Key(string(corev1.ResourceStorage))
+	request, ok := pvc.Resources.Requests[corev1.ResourceStorage]
+	if !ok {
+		return append(errs, field.Required(requestPath, "storage must be requested to store data on a persistent volume"))
+	}
+	if request.Sign() <= 0 {
+		errs = append(errs, field.Invalid(requestPath, request.String(), "must be greater than 0"))
+	}
+	if limit, ok := pvc.Resources.Limits[corev1.ResourceStorage]; ok && limit.Cmp(request) < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("resources", "limits").Key(string(corev1.ResourceStorage)),
+			limit.String(), "must be greater than or equal to the storage request"))
+	}
+	return errs
+}
+
+// validateRemoteWrite rejects remote write configurations which Prometheus
+// refuses to load: invalid URLs, duplicate names or endpoints and more than
+// one authentication method per endpoint.
+func validateRemoteWrite(remoteWrites []monv1.RemoteWriteSpec, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	names := map[string]struct{}{}
+	urls := map[string]struct{}{}
+	for i, rw := range remoteWrites {
+		rwPath := fldPath.Index(i)
+
+		u, err := url.Parse(rw.URL)
+		switch {
+		case err != nil:
+			errs = append(errs, field.Invalid(rwPath.Child("url"), rw.URL, err.Error()))
+		case u.Scheme != "http" && u.Scheme != "https":
+			errs = append(errs, field.Invalid(rwPath.Child("url"), rw.URL, "scheme must be http or https"))
+		case u.Host == "":
+			errs = append(errs, field.Invalid(rwPath.Child("url"), rw.URL, "host must not be empty"))
+		}
+
+		if rw.Name != "" {
+			if _, ok := names[rw.Name]; ok {
+				errs = append(errs, field.Duplicate(rwPath.Child("name"), rw.Name))
+			}
+			names[rw.Name] = struct{}{}
+		}
+		if _, ok := urls[rw.URL]; ok {
+			errs = append(errs, field.Duplicate(rwPath.Child("url"), rw.URL))
+		}
+		urls[rw.URL] = struct{}{}
+
+		var auths []string
+		if rw.BasicAuth != nil {
+			auths = append(auths, "basicAuth")
+		}
+		if rw.OAuth2 != nil {
+			auths = append(auths, "oauth2")
+		}
+		if rw.BearerToken != "" {
+			auths = append(auths, "bearerToken")
+		}
+		if rw.BearerTokenFile != "" {
+			auths = append(auths, "bearerTokenFile")
+		}
+		if rw.Authorization != nil {
+			auths = append(auths, "authorization")
+		}
+		if rw.Sigv4 != nil {
+			auths = append(auths, "sigv4")
+		}
+		if len(auths) > 1 {
+			errs = append(errs, field.Forbidden(rwPath, fmt.Sprintf("at most one authentication method can be set, got %v", auths)))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,210 @@
+package webhooks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestDefaultMonitoringStack(t *testing.T) {
+	ms := &stack.MonitoringStack{
+		Spec: stack.MonitoringStackSpec{
+			AlertmanagerConfig: stack.AlertmanagerConfig{
+				Scheduling: &stack.SchedulingConfig{},
+			},
+			Web: &stack.WebConfig{
+				Expose: &stack.ExposeConfig{
+					Prometheus: &stack.ExposedEndpoint{Host: "prometheus.example.com"},
+				},
+			},
+		},
+	}
+	assert.NilError(t, (&monitoringStackDefaulter{}).Default(context.Background(), ms))
+
+	assert.Equal(t, ms.Spec.LogLevel, stack.Info)
+	assert.Equal(t, ms.Spec.Retention, monv1.Duration("120h"))
+	// resources left empty are set explicitly since the API server defaults
+	// unset resources before calling the webhook
+	assert.Equal(t, len(ms.Spec.Resources.Requests), 0)
+	assert.Equal(t, *ms.Spec.PrometheusConfig.Replicas, int32(2))
+	assert.Equal(t, *ms.Spec.AlertmanagerConfig.Replicas, int32(2))
+	assert.Equal(t, len(ms.Spec.AlertmanagerConfig.Resources.Limits), 0)
+	assert.Equal(t, ms.Spec.AlertmanagerConfig.Retention, monv1.GoDuration("120h"))
+	assert.Equal(t, ms.Spec.AlertmanagerConfig.Scheduling.PodAntiAffinity, stack.RequiredPodAntiAffinity)
+	assert.Equal(t, ms.Spec.Web.Expose.Prometheus.Path, "/")
+
+	// fields set by the user are preserved
+	ms = &stack.MonitoringStack{
+		Spec: stack.MonitoringStackSpec{
+			LogLevel:  stack.Debug,
+			Retention: "1d",
+			PrometheusConfig: &stack.PrometheusConfig{
+				Replicas: pointer.Int32(1),
+			},
+			AlertmanagerConfig: stack.AlertmanagerConfig{
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+				},
+			},
+		},
+	}
+	defaultMonitoringStack(ms)
+	assert.Equal(t, ms.Spec.LogLevel, stack.Debug)
+	assert.Equal(t, ms.Spec.Retention, monv1.Duration("1d"))
+	assert.Equal(t, *ms.Spec.PrometheusConfig.Replicas, int32(1))
+	assert.Equal(t, len(ms.Spec.AlertmanagerConfig.Resources.Requests), 0)
+	assert.Equal(t, ms.Spec.AlertmanagerConfig.Resources.Limits.Memory().String(), "1Gi")
+}
+
+func TestValidateMonitoringStack(t *testing.T) {
+	pvc := func(request string, limit string) *corev1.PersistentVolumeClaimSpec {
+		spec := &corev1.PersistentVolumeClaimSpec{}
+		if request != "" {
+			spec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(request)}
+		}
+		if limit != "" {
+			spec.Resources.Limits = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(limit)}
+		}
+		return spec
+	}
+
+	tt := []struct {
+		name   string
+		spec   stack.MonitoringStackSpec
+		errors []string
+	}{
+		{
+			name: "valid",
+			spec: stack.MonitoringStackSpec{
+				Retention:        "30d",
+				ResourceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+				PrometheusConfig: &stack.PrometheusConfig{
+					PersistentVolumeClaim: pvc("10Gi", "20Gi"),
+					RemoteWrite: []monv1.RemoteWriteSpec{
+						{URL: "https://a.example.com/api/v1/write", BasicAuth: &monv1.BasicAuth{}},
+						{URL: "https://b.example.com/api/v1/write"},
+					},
+				},
+				AlertmanagerConfig: stack.AlertmanagerConfig{Retention: "24h"},
+			},
+		},
+		{
+			name: "invalid selectors",
+			spec: stack.MonitoringStackSpec{
+				ResourceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "-invalid"}},
+				NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: metav1.LabelSelectorOpIn},
+				}},
+			},
+			errors: []string{"spec.resourceSelector.matchLabels", "spec.namespaceSelector.matchExpressions[0].values"},
+		},
+		{
+			name: "incomplete object storage",
+			spec: stack.MonitoringStackSpec{
+				ObjectStorage: &stack.ObjectStorageConfig{
+					Secret: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "thanos-objstore"}},
+				},
+			},
+			errors: []string{"spec.objectStorage.secret.key"},
+		},
+		{
+			name: "invalid retention",
+			spec: stack.MonitoringStackSpec{
+				Retention:          "0d",
+				AlertmanagerConfig: stack.AlertmanagerConfig{Retention: "1d"},
+			},
+			errors: []string{"spec.retention", "spec.alertmanagerConfig.retention"},
+		},
+		{
+			name: "impossible persistent volume claims",
+			spec: stack.MonitoringStackSpec{
+				PrometheusConfig: &stack.PrometheusConfig{
+					PersistentVolumeClaim: pvc("10Gi", "5Gi"),
+				},
+				AlertmanagerConfig: stack.AlertmanagerConfig{
+					PersistentVolumeClaim: pvc("", ""),
+				},
+			},
+			errors: []string{
+				"spec.prometheusConfig.persistentVolumeClaim.resources.limits[storage]",
+				"spec.alertmanagerConfig.persistentVolumeClaim.resources.requests[storage]",
+			},
+		},
+		{
+			name: "conflicting remote writes",
+			spec: stack.MonitoringStackSpec{
+				PrometheusConfig: &stack.PrometheusConfig{
+					RemoteWrite: []monv1.RemoteWriteSpec{
+						{Name: "remote", URL: "https://a.example.com/api/v1/write"},
+						{Name: "remote", URL: "https://a.example.com/api/v1/write"},
+						{URL: "a.example.com"},
+						{URL: "https://c.example.com", BearerToken: "token", BasicAuth: &monv1.BasicAuth{}},
+					},
+				},
+			},
+			errors: []string{
+				"spec.prometheusConfig.remoteWrite[1].name",
+				"spec.prometheusConfig.remoteWrite[1].url",
+				"spec.prometheusConfig.remoteWrite[2].url",
+				"spec.prometheusConfig.remoteWrite[3]",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ms := &stack.MonitoringStack{
+				ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "ns"},
+				Spec:       tc.spec,
+			}
+			errs := validateMonitoringStack(ms)
+			var fields []string
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			assert.DeepEqual(t, fields, tc.errors)
+
+			err := (&monitoringStackValidator{}).ValidateCreate(context.Background(), ms)
+			assert.Equal(t, apierrors.IsInvalid(err), len(tc.errors) > 0)
+		})
+	}
+}
+
+func TestValidateMonitoringStackUpdate(t *testing.T) {
+	ctx := context.Background()
+	v := &monitoringStackValidator{}
+	// the stack was accepted before duplicate remote writes were rejected
+	old := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "ns", Finalizers: []string{"monitoring.rhobs/cleanup"}},
+		Spec: stack.MonitoringStackSpec{
+			PrometheusConfig: &stack.PrometheusConfig{
+				RemoteWrite: []monv1.RemoteWriteSpec{
+					{URL: "https://a.example.com/api/v1/write"},
+					{URL: "https://a.example.com/api/v1/write"},
+				},
+			},
+		},
+	}
+	assert.Assert(t, apierrors.IsInvalid(v.ValidateCreate(ctx, old)))
+
+	// its finalizer can still be removed
+	updated := old.DeepCopy()
+	updated.Finalizers = nil
+	assert.NilError(t, v.ValidateUpdate(ctx, old, updated))
+
+	// but changes of its spec are validated unless it is being deleted
+	updated.Spec.LogLevel = stack.Debug
+	assert.Assert(t, apierrors.IsInvalid(v.ValidateUpdate(ctx, old, updated)))
+	updated.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	assert.NilError(t, v.ValidateUpdate(ctx, old, updated))
+}
@@ -0,0 +1,293 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	"github.com/prometheus/common/model"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+type thanosQuerierValidator struct{}
+
+func (v *thanosQuerierValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return v.validate(obj)
+}
+
+// ValidateUpdate only validates changes of the spec, so that the metadata of
+// ThanosQueriers which were accepted by earlier rules can still be updated,
+// e.g. to remove their finalizers.
+func (v *thanosQuerierValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	old, ok := oldObj.(*stack.ThanosQuerier)
+	if !ok {
+		return fmt.Errorf("expected a ThanosQuerier but got %T", oldObj)
+	}
+	querier, ok := newObj.(*stack.ThanosQuerier)
+	if !ok {
+		return fmt.Errorf("expected a ThanosQuerier but got %T", newObj)
+	}
+	if !querier.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(old.Spec, querier.Spec) {
+		return nil
+	}
+	errs := append(validateThanosQuerier(querier), validateThanosQuerierUpdate(old, querier)...)
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(stack.GroupVersion.WithKind("ThanosQuerier").GroupKind(), querier.Name, errs)
+	}
+	return nil
+}
+
+func (v *thanosQuerierValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func (v *thanosQuerierValidator) validate(obj runtime.Object) error {
+	querier, ok := obj.(*stack.ThanosQuerier)
+	if !ok {
+		return fmt.Errorf("expected a ThanosQuerier but got %T", obj)
+	}
+	if errs := validateThanosQuerier(querier); len(errs) > 0 {
+		return apierrors.NewInvalid(stack.GroupVersion.WithKind("ThanosQuerier").GroupKind(), querier.Name, errs)
+	}
+	return nil
+}
+
+func validateThanosQuerier(querier *stack.ThanosQuerier) field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := metav1validation.ValidateLabelSelector(&querier.Spec.Selector,
+		metav1validation.LabelSelectorValidationOptions{}, specPath.Child("selector"))
+
+	nsPath := specPath.Child("namespaceSelector")
+	nsSelector := querier.Spec.NamespaceSelector
+	if nsSelector.Any && len(nsSelector.MatchNames) > 0 {
+		errs = append(errs, field.Forbidden(nsPath.Child("matchNames"), "must be empty when any is true"))
+	}
+	if nsSelector.LabelSelector != nil {
+		labelSelectorPath := nsPath.Child("labelSelector")
+		if nsSelector.Any || len(nsSelector.MatchNames) > 0 {
+			errs = append(errs, field.Forbidden(labelSelectorPath, "can't be combined with any or matchNames"))
+		}
+		errs = append(errs, metav1validation.ValidateLabelSelector(nsSelector.LabelSelector,
+			metav1validation.LabelSelectorValidationOptions{}, labelSelectorPath)...)
+	}
+	names := map[string]struct{}{}
+	for i, name := range nsSelector.MatchNames {
+		namePath := nsPath.Child("matchNames").Index(i)
+		for _, msg := range validation.IsDNS1123Label(name) {
+			errs = append(errs, field.Invalid(namePath, name, msg))
+		}
+		if _, ok := names[name]; ok {
+			errs = append(errs, field.Duplicate(namePath, name))
+		}
+		names[name] = struct{}{}
+	}
+
+	labels := map[string]struct{}{}
+	for i, label := range querier.Spec.ReplicaLabels {
+		labelPath := specPath.Child("replicaLabels").Index(i)
+		if !model.LabelName(label).IsValid() {
+			errs = append(errs, field.Invalid(labelPath, label, "must be a valid Prometheus label name"))
+		}
+		if _, ok := labels[label]; ok {
+			errs = append(errs, field.Duplicate(labelPath, label))
+		}
+		labels[label] = struct{}{}
+	}
+
+	httpPort, grpcPort := querier.Spec.HTTPPort, querier.Spec.GRPCPort
+	if httpPort != nil && grpcPort != nil && *httpPort == *grpcPort {
+		errs = append(errs, field.Invalid(specPath.Child("grpcPort"), *grpcPort, "must differ from httpPort"))
+	}
+
+	for i, endpoint := range querier.Spec.AdditionalEndpoints {
+		errs = append(errs, validateThanosEndpoint(endpoint, specPath.Child("additionalEndpoints").Index(i))...)
+	}
+
+	if frontend := querier.Spec.QueryFrontend; frontend != nil {
+		errs = append(errs, validateQueryFrontend(frontend, specPath.Child("queryFrontend"))...)
+	}
+
+	if os := querier.Spec.ObjectStorage; os != nil {
+		errs = append(errs, validateThanosObjectStorage(querier, specPath.Child("objectStorage"))...)
+	}
+
+	if query := querier.Spec.Query; query != nil {
+		queryPath := specPath.Child("query")
+		errs = append(errs, validateQueryDuration(query.Timeout, queryPath.Child("timeout"))...)
+		errs = append(errs, validateQueryDuration(query.LookbackDelta, queryPath.Child("lookbackDelta"))...)
+		errs = append(errs, validateQueryDuration(query.DefaultEvaluationInterval, queryPath.Child("defaultEvaluationInterval"))...)
+		if query.MaxConcurrent != nil && *query.MaxConcurrent < 1 {
+			errs = append(errs, field.Invalid(queryPath.Child("maxConcurrent"), *query.MaxConcurrent, "must be greater than 0"))
+		}
+	}
+	return errs
+}
+
+// validateThanosQuerierUpdate rejects changes of the persistent volume claims
+// of the store gateway and the compactor, since the volume claim templates of
+// their stateful sets are immutable. The claims can be changed by removing the
+// component first.
+func validateThanosQuerierUpdate(old *stack.ThanosQuerier, querier *stack.ThanosQuerier) field.ErrorList {
+	oldOS, os := old.Spec.ObjectStorage, querier.Spec.ObjectStorage
+	if oldOS == nil || os == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	osPath := field.NewPath("spec", "objectStorage")
+	if oldOS.StoreGateway != nil && os.StoreGateway != nil {
+		errs = append(errs, apivalidation.ValidateImmutableField(os.StoreGateway.PersistentVolumeClaim,
+			oldOS.StoreGateway.PersistentVolumeClaim, osPath.Child("storeGateway", "persistentVolumeClaim"))...)
+	}
+	if oldOS.Compactor != nil && os.Compactor != nil {
+		errs = append(errs, apivalidation.ValidateImmutableField(os.Compactor.PersistentVolumeClaim,
+			oldOS.Compactor.PersistentVolumeClaim, osPath.Child("compactor", "persistentVolumeClaim"))...)
+	}
+	return errs
+}
+
+func validateThanosEndpoint(endpoint stack.ThanosEndpoint, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	set := 0
+	if endpoint.Address != "" {
+		set++
+		errs = append(errs, validateHostPort(endpoint.Address, fldPath.Child("address"))...)
+	}
+	if endpoint.DNSSRVName != "" {
+		set++
+		// the service and protocol labels of SRV names start with an underscore
+		for _, label := range strings.Split(endpoint.DNSSRVName, ".") {
+			if msgs := validation.IsDNS1123Label(strings.TrimPrefix(label, "_")); len(msgs) > 0 {
+				errs = append(errs, field.Invalid(fldPath.Child("dnsSRVName"), endpoint.DNSSRVName, strings.Join(msgs, ", ")))
+				break
+			}
+		}
+	}
+	if svc := endpoint.Service; svc != nil {
+		set++
+		svcPath := fldPath.Child("service")
+		for _, msg := range validation.IsDNS1035Label(svc.Name) {
+			errs = append(errs, field.Invalid(svcPath.Child("name"), svc.Name, msg))
+		}
+		if svc.Namespace != "" {
+			for _, msg := range validation.IsDNS1123Label(svc.Namespace) {
+				errs = append(errs, field.Invalid(svcPath.Child("namespace"), svc.Namespace, msg))
+			}
+		}
+		if svc.Port != "" {
+			for _, msg := range validation.IsValidPortName(svc.Port) {
+				errs = append(errs, field.Invalid(svcPath.Child("port"), svc.Port, msg))
+			}
+		}
+	}
+	if set != 1 {
+		errs = append(errs, field.Invalid(fldPath, endpoint, "exactly one of address, dnsSRVName and service must be set"))
+	}
+	return errs
+}
+
+func validateThanosObjectStorage(querier *stack.ThanosQuerier, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	os := querier.Spec.ObjectStorage
+	secretPath := fldPath.Child("secret")
+	if os.Secret.Name == "" {
+		errs = append(errs, field.Required(secretPath.Child("name"), "the name of the secret is required"))
+	}
+	if os.Secret.Key == "" {
+		errs = append(errs, field.Required(secretPath.Child("key"), "the key of the object storage config is required"))
+	}
+
+	// the client TLS config of the querier applies to all endpoints, while
+	// the store gateway serves plain gRPC
+	if os.StoreGateway != nil && querier.Spec.TLS != nil {
+		errs = append(errs, field.Forbidden(fldPath.Child("storeGateway"), "can't be combined with tls"))
+	}
+
+	if compactor := os.Compactor; compactor != nil && compactor.Retention != nil {
+		retentionPath := fldPath.Child("compactor", "retention")
+		errs = append(errs, validateRetention(compactor.Retention.Raw, retentionPath.Child("raw"))...)
+		errs = append(errs, validateRetention(compactor.Retention.FiveMinutes, retentionPath.Child("fiveMinutes"))...)
+		errs = append(errs, validateRetention(compactor.Retention.OneHour, retentionPath.Child("oneHour"))...)
+	}
+	return errs
+}
+
+// validateRetention rejects retentions which Thanos can't parse. A retention
+// of 0 retains blocks forever.
+func validateRetention(d monv1.Duration, fldPath *field.Path) field.ErrorList {
+	if d == "" {
+		return nil
+	}
+	if _, err := model.ParseDuration(string(d)); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, d, err.Error())}
+	}
+	return nil
+}
+
+func validateQueryFrontend(frontend *stack.ThanosQueryFrontendConfig, fldPath *field.Path) field.ErrorList {
+	errs := validateQueryDuration(frontend.SplitInterval, fldPath.Child("splitInterval"))
+	cache := frontend.Cache
+	if cache == nil {
+		return errs
+	}
+
+	cachePath := fldPath.Child("cache")
+	set := 0
+	if cache.InMemory != nil {
+		set++
+	}
+	if cache.Memcached != nil {
+		set++
+		if len(cache.Memcached.Addresses) == 0 {
+			errs = append(errs, field.Required(cachePath.Child("memcached", "addresses"), "at least one address is required"))
+		}
+		for i, address := range cache.Memcached.Addresses {
+			errs = append(errs, validateHostPort(address, cachePath.Child("memcached", "addresses").Index(i))...)
+		}
+	}
+	if cache.Redis != nil {
+		set++
+		errs = append(errs, validateHostPort(cache.Redis.Address, cachePath.Child("redis", "address"))...)
+	}
+	if set != 1 {
+		errs = append(errs, field.Invalid(cachePath, cache, "exactly one of inMemory, memcached and redis must be set"))
+	}
+	return errs
+}
+
+func validateHostPort(address string, fldPath *field.Path) field.ErrorList {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, address, err.Error())}
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, address, "must have a valid port number")}
+	}
+	return nil
+}
+
+// validateQueryDuration rejects durations which Thanos can't parse or which
+// are not positive. An unset duration is left to the defaults of Thanos.
+func validateQueryDuration(d *monv1.Duration, fldPath *field.Path) field.ErrorList {
+	if d == nil {
+		return nil
+	}
+	parsed, err := model.ParseDuration(string(*d))
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, *d, err.Error())}
+	}
+	if parsed <= 0 {
+		return field.ErrorList{field.Invalid(fldPath, *d, "must be greater than 0")}
+	}
+	return nil
+}
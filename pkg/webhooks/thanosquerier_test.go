@@ -0,0 +1,285 @@
+package webhooks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestValidateThanosQuerier(t *testing.T) {
+	tt := []struct {
+		name   string
+		spec   stack.ThanosQuerierSpec
+		errors []string
+	}{
+		{
+			name: "valid",
+			spec: stack.ThanosQuerierSpec{
+				Selector:          metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+				NamespaceSelector: stack.NamespaceSelector{MatchNames: []string{"a", "b"}},
+				ReplicaLabels:     []string{"prometheus_replica", "rule_replica"},
+			},
+		},
+		{
+			name: "invalid selector",
+			spec: stack.ThanosQuerierSpec{
+				Selector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: "Matches", Values: []string{"demo"}},
+				}},
+			},
+			errors: []string{"spec.selector.matchExpressions[0].operator"},
+		},
+		{
+			name: "conflicting namespace selector",
+			spec: stack.ThanosQuerierSpec{
+				NamespaceSelector: stack.NamespaceSelector{Any: true, MatchNames: []string{"a", "a", "Invalid"}},
+			},
+			errors: []string{
+				"spec.namespaceSelector.matchNames",
+				"spec.namespaceSelector.matchNames[1]",
+				"spec.namespaceSelector.matchNames[2]",
+			},
+		},
+		{
+			name: "namespace label selector",
+			spec: stack.ThanosQuerierSpec{
+				NamespaceSelector: stack.NamespaceSelector{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+				},
+			},
+		},
+		{
+			name: "conflicting namespace label selector",
+			spec: stack.ThanosQuerierSpec{
+				NamespaceSelector: stack.NamespaceSelector{
+					MatchNames:    []string{"a"},
+					LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "-a"}},
+				},
+			},
+			errors: []string{
+				"spec.namespaceSelector.labelSelector",
+				"spec.namespaceSelector.labelSelector.matchLabels",
+			},
+		},
+		{
+			name: "invalid replica labels",
+			spec: stack.ThanosQuerierSpec{
+				ReplicaLabels: []string{"replica", "replica", "prometheus-replica", ""},
+			},
+			errors: []string{
+				"spec.replicaLabels[1]",
+				"spec.replicaLabels[2]",
+				"spec.replicaLabels[3]",
+			},
+		},
+		{
+			name: "valid query config",
+			spec: stack.ThanosQuerierSpec{
+				Query: &stack.ThanosQueryConfig{
+					Timeout:                 durationPtr("5m"),
+					MaxConcurrent:           func(i int32) *int32 { return &i }(10),
+					PartialResponseStrategy: stack.AbortPartialResponse,
+				},
+			},
+		},
+		{
+			name: "invalid query config",
+			spec: stack.ThanosQuerierSpec{
+				Query: &stack.ThanosQueryConfig{
+					Timeout:                   durationPtr("0s"),
+					LookbackDelta:             durationPtr("5 minutes"),
+					DefaultEvaluationInterval: durationPtr("1m"),
+					MaxConcurrent:             func(i int32) *int32 { return &i }(0),
+				},
+			},
+			errors: []string{
+				"spec.query.timeout",
+				"spec.query.lookbackDelta",
+				"spec.query.maxConcurrent",
+			},
+		},
+		{
+			name: "conflicting ports",
+			spec: stack.ThanosQuerierSpec{
+				HTTPPort: func(i int32) *int32 { return &i }(9090),
+				GRPCPort: func(i int32) *int32 { return &i }(9090),
+			},
+			errors: []string{"spec.grpcPort"},
+		},
+		{
+			name: "valid additional endpoints",
+			spec: stack.ThanosQuerierSpec{
+				AdditionalEndpoints: []stack.ThanosEndpoint{
+					{Address: "thanos-store.example.com:10901"},
+					{DNSSRVName: "_grpc._tcp.thanos-store.monitoring.svc.cluster.local"},
+					{Service: &stack.ThanosEndpointService{Name: "thanos-ruler", Namespace: "monitoring"}},
+				},
+			},
+		},
+		{
+			name: "invalid additional endpoints",
+			spec: stack.ThanosQuerierSpec{
+				AdditionalEndpoints: []stack.ThanosEndpoint{
+					{Address: "thanos-store.example.com"},
+					{DNSSRVName: "_grpc._tcp.thanos-store", Address: "thanos-store:10901"},
+					{Service: &stack.ThanosEndpointService{Name: "Thanos", Port: "grpc_port"}},
+					{},
+				},
+			},
+			errors: []string{
+				"spec.additionalEndpoints[0].address",
+				"spec.additionalEndpoints[1]",
+				"spec.additionalEndpoints[2].service.name",
+				"spec.additionalEndpoints[2].service.port",
+				"spec.additionalEndpoints[3]",
+			},
+		},
+		{
+			name: "valid query frontend",
+			spec: stack.ThanosQuerierSpec{
+				QueryFrontend: &stack.ThanosQueryFrontendConfig{
+					SplitInterval: durationPtr("12h"),
+					Cache: &stack.QueryFrontendCacheConfig{
+						Memcached: &stack.MemcachedCacheConfig{Addresses: []string{"memcached-0:11211", "memcached-1:11211"}},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid query frontend",
+			spec: stack.ThanosQuerierSpec{
+				QueryFrontend: &stack.ThanosQueryFrontendConfig{
+					SplitInterval: durationPtr("0h"),
+					Cache: &stack.QueryFrontendCacheConfig{
+						InMemory: &stack.InMemoryCacheConfig{},
+						Redis:    &stack.RedisCacheConfig{Address: "redis"},
+					},
+				},
+			},
+			errors: []string{
+				"spec.queryFrontend.splitInterval",
+				"spec.queryFrontend.cache.redis.address",
+				"spec.queryFrontend.cache",
+			},
+		},
+		{
+			name: "valid object storage",
+			spec: stack.ThanosQuerierSpec{
+				ObjectStorage: &stack.ThanosObjectStorageConfig{
+					Secret: corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "objstore"},
+						Key:                  "thanos.yaml",
+					},
+					StoreGateway: &stack.StoreGatewayConfig{},
+					Compactor: &stack.CompactorConfig{
+						Retention: &stack.RetentionByResolution{Raw: "30d", FiveMinutes: "0d"},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid object storage",
+			spec: stack.ThanosQuerierSpec{
+				TLS: &stack.ThanosQuerierTLSConfig{},
+				ObjectStorage: &stack.ThanosObjectStorageConfig{
+					StoreGateway: &stack.StoreGatewayConfig{},
+					Compactor: &stack.CompactorConfig{
+						Retention: &stack.RetentionByResolution{OneHour: "1 year"},
+					},
+				},
+			},
+			errors: []string{
+				"spec.objectStorage.secret.name",
+				"spec.objectStorage.secret.key",
+				"spec.objectStorage.storeGateway",
+				"spec.objectStorage.compactor.retention.oneHour",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			querier := &stack.ThanosQuerier{
+				ObjectMeta: metav1.ObjectMeta{Name: "querier", Namespace: "ns"},
+				Spec:       tc.spec,
+			}
+			errs := validateThanosQuerier(querier)
+			var fields []string
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			assert.DeepEqual(t, fields, tc.errors)
+
+			err := (&thanosQuerierValidator{}).ValidateCreate(context.Background(), querier)
+			assert.Equal(t, apierrors.IsInvalid(err), len(tc.errors) > 0)
+		})
+	}
+}
+
+func TestValidateThanosQuerierUpdate(t *testing.T) {
+	ctx := context.Background()
+	v := &thanosQuerierValidator{}
+	// the querier was accepted before its endpoint became invalid
+	old := &stack.ThanosQuerier{
+		ObjectMeta: metav1.ObjectMeta{Name: "querier", Namespace: "ns"},
+		Spec: stack.ThanosQuerierSpec{
+			AdditionalEndpoints: []stack.ThanosEndpoint{{Address: "thanos-store.example.com"}},
+		},
+	}
+	assert.Assert(t, apierrors.IsInvalid(v.ValidateCreate(ctx, old)))
+
+	// its metadata can still be updated
+	updated := old.DeepCopy()
+	updated.Labels = map[string]string{"key": "value"}
+	assert.NilError(t, v.ValidateUpdate(ctx, old, updated))
+
+	// but changes of its spec are validated unless it is being deleted
+	updated.Spec.Replicas = pointer.Int32(2)
+	assert.Assert(t, apierrors.IsInvalid(v.ValidateUpdate(ctx, old, updated)))
+	updated.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	assert.NilError(t, v.ValidateUpdate(ctx, old, updated))
+}
+
+func TestValidateThanosQuerierPersistentVolumeClaimUpdate(t *testing.T) {
+	ctx := context.Background()
+	v := &thanosQuerierValidator{}
+	old := &stack.ThanosQuerier{
+		ObjectMeta: metav1.ObjectMeta{Name: "querier", Namespace: "ns"},
+		Spec: stack.ThanosQuerierSpec{
+			ObjectStorage: &stack.ThanosObjectStorageConfig{
+				Secret: corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "thanos-objstore"},
+					Key:                  "objstore.yaml",
+				},
+				StoreGateway: &stack.StoreGatewayConfig{},
+				Compactor:    &stack.CompactorConfig{},
+			},
+		},
+	}
+
+	// the volume claim templates of the stateful sets are immutable
+	updated := old.DeepCopy()
+	updated.Spec.ObjectStorage.StoreGateway.PersistentVolumeClaim = &corev1.PersistentVolumeClaimSpec{}
+	assert.Assert(t, apierrors.IsInvalid(v.ValidateUpdate(ctx, old, updated)))
+	updated = old.DeepCopy()
+	updated.Spec.ObjectStorage.Compactor.PersistentVolumeClaim = &corev1.PersistentVolumeClaimSpec{}
+	assert.Assert(t, apierrors.IsInvalid(v.ValidateUpdate(ctx, old, updated)))
+
+	// but the claim can be set together with the component
+	old.Spec.ObjectStorage.Compactor = nil
+	assert.NilError(t, v.ValidateUpdate(ctx, old, updated))
+	assert.NilError(t, v.ValidateCreate(ctx, updated))
+}
+
+func durationPtr(d monv1.Duration) *monv1.Duration {
+	return &d
+}
@@ -0,0 +1,33 @@
+package webhooks
+
+import (
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// RBAC for injecting the CA bundle into the webhook configurations and the
+// conversion webhooks of the CRDs
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations;validatingwebhookconfigurations,verbs=get;update
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;update
+
+// RegisterWithManager registers the defaulting and validating webhooks of
+// MonitoringStack and ThanosQuerier with the webhook server of the manager.
+// The webhooks are served under the paths generated by controller-runtime,
+// e.g. /validate-monitoring-rhobs-v1alpha1-monitoringstack. The conversion
+// webhook between the API versions is served under /convert since v1alpha1
+// is the hub of the conversions.
+func RegisterWithManager(mgr ctrl.Manager) error {
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&stack.MonitoringStack{}).
+		WithDefaulter(&monitoringStackDefaulter{}).
+		WithValidator(&monitoringStackValidator{}).
+		Complete(); err != nil {
+		return err
+	}
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&stack.ThanosQuerier{}).
+		WithValidator(&thanosQuerierValidator{}).
+		Complete()
+}
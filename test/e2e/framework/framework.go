@@ -3,6 +3,7 @@ package framework
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -76,6 +77,28 @@ func (f *Framework) StartServicePortForward(serviceName string, ns string, port
 	return f.StartPortForward(pods[0].Name, ns, port, stopChan)
 }
 
+// QueryThroughService runs an instant query against the Prometheus API of a
+// service. The API server proxies the request to the endpoints of the service.
+func (f *Framework) QueryThroughService(serviceName string, ns string, port string, query string) (*PrometheusResponse, error) {
+	c, err := f.getKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.CoreV1().Services(ns).
+		ProxyGet("http", serviceName, port, "/api/v1/query", map[string]string{"query": query}).
+		DoRaw(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("unable to query service %s/%s: %v", ns, serviceName, err)
+	}
+
+	var result PrometheusResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("unable to parse query response: %v", err)
+	}
+	return &result, nil
+}
+
 func (f *Framework) GetStatefulSetPods(name string, namespace string) ([]corev1.Pod, error) {
 	var svc appsv1.StatefulSet
 	key := types.NamespacedName{
@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 
 	"github.com/rhobs/observability-operator/test/e2e/framework"
 
@@ -81,6 +84,12 @@ func TestMonitoringStackController(t *testing.T) {
 	}, {
 		name:     "single prometheus replica has no pdb",
 		scenario: singlePrometheusReplicaHasNoPDB,
+	}, {
+		name:     "single alertmanager replica has no pdb",
+		scenario: singleAlertmanagerReplicaHasNoPDB,
+	}, {
+		name:     "Prometheus is exposed through an Ingress",
+		scenario: assertPrometheusIsExposed,
 	}, {
 		name:     "Prometheus stacks can scrape themselves",
 		scenario: assertPrometheusScrapesItself,
@@ -117,6 +126,9 @@ func TestMonitoringStackController(t *testing.T) {
 	}, {
 		name:     "managed fields in Prometheus object",
 		scenario: assertPrometheusManagedFields,
+	}, {
+		name:     "Cluster-scoped resources are removed with the stack",
+		scenario: assertClusterScopedResourcesAreDeleted,
 	}}
 
 	for _, tc := range ts {
@@ -246,6 +258,12 @@ func reconcileStack(t *testing.T) {
 	assertCondition(t, availableC, monitoringstack.AvailableReason, stack.AvailableCondition, availableMs)
 	reconciledC := getConditionByType(availableMs.Status.Conditions, stack.ReconciledCondition)
 	assertCondition(t, reconciledC, monitoringstack.ReconciledReason, stack.ReconciledCondition, availableMs)
+	prometheusC := getConditionByType(availableMs.Status.Conditions, stack.PrometheusAvailableCondition)
+	assertCondition(t, prometheusC, monitoringstack.PrometheusAvailableReason, stack.PrometheusAvailableCondition, availableMs)
+	alertmanagerC := getConditionByType(availableMs.Status.Conditions, stack.AlertmanagerAvailableCondition)
+	assertCondition(t, alertmanagerC, monitoringstack.AlertmanagerAvailableReason, stack.AlertmanagerAvailableCondition, availableMs)
+	sidecarC := getConditionByType(availableMs.Status.Conditions, stack.ThanosSidecarReadyCondition)
+	assertCondition(t, sidecarC, monitoringstack.ThanosSidecarReadyReason, stack.ThanosSidecarReadyCondition, availableMs)
 }
 
 func assertCondition(t *testing.T, c *stack.Condition, reason string, ctype stack.ConditionType, ms stack.MonitoringStack) {
@@ -403,6 +421,74 @@ func singlePrometheusReplicaHasNoPDB(t *testing.T) {
 	f.AssertResourceNeverExists(pdbName, ms.Namespace, &pdb)(t)
 }
 
+func singleAlertmanagerReplicaHasNoPDB(t *testing.T) {
+	// asserts that no alertmanager pdb is created for stacks with alertmanager replicas set to 1
+	ms := newMonitoringStack(t, "single-am-replica")
+
+	err := f.K8sClient.Create(context.Background(), ms)
+	assert.NilError(t, err, "failed to create a monitoring stack")
+
+	// ensure pdb is created for default stack
+	pdb := policyv1.PodDisruptionBudget{}
+	pdbName := ms.Name + "-alertmanager"
+	f.AssertResourceEventuallyExists(pdbName, ms.Namespace, &pdb)(t)
+
+	// Update replica count to 1 and assert that pdb is removed
+	key := types.NamespacedName{Name: ms.Name, Namespace: ms.Namespace}
+	err = f.K8sClient.Get(context.Background(), key, ms)
+	assert.NilError(t, err, "failed to get a monitoring stack")
+
+	ms.Spec.AlertmanagerConfig.Replicas = intPtr(1)
+	err = f.K8sClient.Update(context.Background(), ms)
+	assert.NilError(t, err, "failed to update monitoring stack")
+
+	// ensure there is no pdb
+	f.AssertResourceNeverExists(pdbName, ms.Namespace, &pdb)(t)
+
+	am := monv1.Alertmanager{}
+	f.GetResourceWithRetry(t, ms.Name, ms.Namespace, &am)
+	assert.Equal(t, *am.Spec.Replicas, int32(1))
+}
+
+func assertPrometheusIsExposed(t *testing.T) {
+	ms := newMonitoringStack(t, "exposed-prometheus")
+	ms.Spec.Web = &stack.WebConfig{
+		Expose: &stack.ExposeConfig{
+			Prometheus: &stack.ExposedEndpoint{
+				Host: "prometheus.example.com",
+				Path: "/prometheus",
+			},
+		},
+	}
+	err := f.K8sClient.Create(context.Background(), ms)
+	assert.NilError(t, err, "failed to create a monitoring stack")
+
+	ingress := networkingv1.Ingress{}
+	name := ms.Name + "-prometheus"
+	f.AssertResourceEventuallyExists(name, ms.Namespace, &ingress)(t)
+	assert.Equal(t, ingress.Spec.Rules[0].Host, "prometheus.example.com")
+	assert.Equal(t, ingress.Spec.Rules[0].HTTP.Paths[0].Path, "/prometheus")
+
+	prom := monv1.Prometheus{}
+	f.GetResourceWithRetry(t, ms.Name, ms.Namespace, &prom)
+	assert.Equal(t, prom.Spec.ExternalURL, "http://prometheus.example.com/prometheus")
+	assert.Equal(t, prom.Spec.RoutePrefix, "/prometheus")
+
+	// no ingress is created for alertmanager
+	f.AssertResourceNeverExists(ms.Name+"-alertmanager", ms.Namespace, &networkingv1.Ingress{})(t)
+
+	// removing the expose config deletes the ingress
+	key := types.NamespacedName{Name: ms.Name, Namespace: ms.Namespace}
+	err = f.K8sClient.Get(context.Background(), key, ms)
+	assert.NilError(t, err, "failed to get a monitoring stack")
+
+	ms.Spec.Web = nil
+	err = f.K8sClient.Update(context.Background(), ms)
+	assert.NilError(t, err, "failed to update monitoring stack")
+
+	f.AssertResourceNeverExists(name, ms.Namespace, &ingress)(t)
+}
+
 func assertPrometheusScrapesItself(t *testing.T) {
 	ms := newMonitoringStack(t, "self-scrape")
 	err := f.K8sClient.Create(context.Background(), ms)
@@ -655,6 +741,47 @@ func assertPrometheusManagedFields(t *testing.T) {
 	assert.DeepEqual(t, have, expected)
 }
 
+func assertClusterScopedResourcesAreDeleted(t *testing.T) {
+	ms := newMonitoringStack(t, "cluster-scoped-cleanup",
+		msNamespaceSelector(map[string]string{"monitoring.rhobs/stack": "cluster-scoped-cleanup"}))
+	err := f.K8sClient.Create(context.Background(), ms)
+	assert.NilError(t, err, "failed to create a monitoring stack")
+
+	// cluster-scoped names are derived from the namespace and name of the
+	// stack, look up the binding through its service account subject instead
+	var crb *rbacv1.ClusterRoleBinding
+	err = wait.Poll(5*time.Second, wait.ForeverTestTimeout, func() (bool, error) {
+		crb, err = clusterRoleBindingForServiceAccount(ms.Name+"-prometheus", ms.Namespace)
+		return crb != nil, err
+	})
+	assert.NilError(t, err, "cluster role binding for prometheus was not created")
+	assert.Assert(t, !strings.HasPrefix(crb.Name, ms.Name), "cluster role binding must be qualified with the namespace")
+	f.AssertResourceEventuallyExists(crb.RoleRef.Name, "", &rbacv1.ClusterRole{})(t)
+
+	err = f.K8sClient.Delete(context.Background(), ms)
+	assert.NilError(t, err, "failed to delete a monitoring stack")
+	err = waitForStackDeletion(ms.Name)
+	assert.NilError(t, err, "monitoring stack was not deleted")
+
+	f.AssertResourceNeverExists(crb.RoleRef.Name, "", &rbacv1.ClusterRole{})(t)
+	f.AssertResourceNeverExists(crb.Name, "", &rbacv1.ClusterRoleBinding{})(t)
+}
+
+func clusterRoleBindingForServiceAccount(name, namespace string) (*rbacv1.ClusterRoleBinding, error) {
+	var crbs rbacv1.ClusterRoleBindingList
+	if err := f.K8sClient.List(context.Background(), &crbs); err != nil {
+		return nil, err
+	}
+	for i := range crbs.Items {
+		for _, s := range crbs.Items[i].Subjects {
+			if s.Kind == "ServiceAccount" && s.Name == name && s.Namespace == namespace {
+				return &crbs.Items[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
 // Update this json when a new Prometheus field is set by MonitoringStack
 const oboManagedFieldsJson = `
 {
@@ -672,6 +799,7 @@ const oboManagedFieldsJson = `
   "f:externalLabels": {
     "f:key": {}
   },
+  "f:image": {},
   "f:logLevel": {},
   "f:podMetadata": {
     "f:labels": {
@@ -714,9 +842,8 @@ const oboManagedFieldsJson = `
     }
   },
   "f:thanos": {
-    "f:baseImage": {},
-    "f:resources": {},
-    "f:version": {}
+    "f:image": {},
+    "f:resources": {}
   },
   "f:tsdb": {}
 }
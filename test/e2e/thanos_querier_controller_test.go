@@ -34,6 +34,18 @@ func TestThanosQuerierController(t *testing.T) {
 			name:     "Delete resources if matched monitoring stack is deleted",
 			scenario: stackWithSidecarGetsDeleted,
 		},
+		{
+			name:     "Select monitoring stacks by namespace labels",
+			scenario: stackInLabelledNamespace,
+		},
+		{
+			name:     "Query through the querier Service",
+			scenario: queryThroughService,
+		},
+		{
+			name:     "Run a store gateway and a compactor on the filesystem object storage",
+			scenario: storeGatewayAndCompactor,
+		},
 	}
 
 	for _, tc := range ts {
@@ -57,6 +69,107 @@ func stackWithSidecarGetsDeleted(t *testing.T) {
 	waitForServiceDeletion(name)
 }
 
+func stackInLabelledNamespace(t *testing.T) {
+	nsLabels := map[string]string{"monitoring.rhobs/querier": "tq-ns-labels"}
+	ns := newNamespace(t, "tq-ns-labels")
+	ns.SetLabels(nsLabels)
+	err := f.K8sClient.Create(context.Background(), ns)
+	assert.NilError(t, err, "failed to create a namespace")
+
+	tq, ms := newThanosStackCombo(t, "tq-ns-labels")
+	tq.Spec.NamespaceSelector = msov1.NamespaceSelector{
+		LabelSelector: &metav1.LabelSelector{MatchLabels: nsLabels},
+	}
+	ms.Namespace = ns.Name
+	err = f.K8sClient.Create(context.Background(), tq)
+	assert.NilError(t, err, "failed to create a thanos querier")
+	err = f.K8sClient.Create(context.Background(), ms)
+	assert.NilError(t, err, "failed to create a monitoring stack")
+
+	assertThanosQuerierStatus(t, tq, ms)
+}
+
+func queryThroughService(t *testing.T) {
+	tq, ms := newThanosStackCombo(t, "tq-service")
+	err := f.K8sClient.Create(context.Background(), tq)
+	assert.NilError(t, err, "failed to create a thanos querier")
+	err = f.K8sClient.Create(context.Background(), ms)
+	assert.NilError(t, err, "failed to create a monitoring stack")
+
+	assertThanosQuerierStatus(t, tq, ms)
+
+	// the Service only routes to the querier when it listens on the pod IP
+	// and passes its readiness probe
+	name := "thanos-querier-" + tq.Name
+	var lastErr error
+	if err := wait.Poll(5*time.Second, 5*time.Minute, func() (bool, error) {
+		result, err := f.QueryThroughService(name, tq.Namespace, "http", "prometheus_build_info")
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		if len(result.Data.Result) != 2 {
+			lastErr = fmt.Errorf("got %d series, want 2", len(result.Data.Result))
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		t.Fatalf("%v: %v", err, lastErr)
+	}
+}
+
+func storeGatewayAndCompactor(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tq-objstore",
+			Namespace: e2eTestNamespace,
+		},
+		StringData: map[string]string{
+			"thanos.yaml": "type: FILESYSTEM\nconfig:\n  directory: /var/thanos/data/bucket\n",
+		},
+	}
+	err := f.K8sClient.Create(context.Background(), secret)
+	assert.NilError(t, err, "failed to create the object storage secret")
+	f.CleanUp(t, func() {
+		f.K8sClient.Delete(context.Background(), secret)
+	})
+
+	tq := newThanosQuerier(t, "tq-objstore", map[string]string{"stack": "tq-objstore"})
+	tq.Spec.ObjectStorage = &msov1.ThanosObjectStorageConfig{
+		Secret: corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+			Key:                  "thanos.yaml",
+		},
+		StoreGateway: &msov1.StoreGatewayConfig{},
+		Compactor: &msov1.CompactorConfig{
+			Retention: &msov1.RetentionByResolution{Raw: "30d"},
+		},
+	}
+	err = f.K8sClient.Create(context.Background(), tq)
+	assert.NilError(t, err, "failed to create a thanos querier")
+
+	f.AssertStatefulsetReady("thanos-store-gateway-"+tq.Name, tq.Namespace, framework.WithTimeout(5*time.Minute))(t)
+	f.AssertStatefulsetReady("thanos-compactor-"+tq.Name, tq.Namespace, framework.WithTimeout(5*time.Minute))(t)
+
+	want := fmt.Sprintf("dnssrv+_grpc._tcp.thanos-store-gateway-%s.%s.svc.cluster.local", tq.Name, tq.Namespace)
+	var lastErr error
+	if err := wait.Poll(5*time.Second, 2*time.Minute, func() (bool, error) {
+		querier := msov1.ThanosQuerier{}
+		key := types.NamespacedName{Name: tq.Name, Namespace: tq.Namespace}
+		if err := f.K8sClient.Get(context.Background(), key, &querier); err != nil {
+			lastErr = err
+			return false, nil
+		}
+		if len(querier.Status.Endpoints) != 1 || querier.Status.Endpoints[0] != want {
+			lastErr = fmt.Errorf("endpoints %v, want [%s]", querier.Status.Endpoints, want)
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		t.Fatalf("%v: %v", err, lastErr)
+	}
+}
+
 func singleStackWithSidecar(t *testing.T) {
 	tq, ms := newThanosStackCombo(t, "tq-ms-combo")
 	err := f.K8sClient.Create(context.Background(), tq)
@@ -72,6 +185,8 @@ func singleStackWithSidecar(t *testing.T) {
 	thanosService := corev1.Service{}
 	f.GetResourceWithRetry(t, name, tq.Namespace, &thanosService)
 
+	assertThanosQuerierStatus(t, tq, ms)
+
 	// Assert prometheus instance can be queried
 	stopChan := make(chan struct{})
 	defer close(stopChan)
@@ -116,6 +231,42 @@ func singleStackWithSidecar(t *testing.T) {
 	}
 }
 
+func assertThanosQuerierStatus(t *testing.T, tq *msov1.ThanosQuerier, ms *msov1.MonitoringStack) {
+	var lastErr error
+	err := wait.Poll(5*time.Second, 5*time.Minute, func() (bool, error) {
+		querier := msov1.ThanosQuerier{}
+		key := types.NamespacedName{Name: tq.Name, Namespace: tq.Namespace}
+		if err := f.K8sClient.Get(context.Background(), key, &querier); err != nil {
+			lastErr = err
+			return false, nil
+		}
+
+		if querier.Status.ObservedGeneration != querier.Generation {
+			lastErr = fmt.Errorf("observed generation %d, want %d", querier.Status.ObservedGeneration, querier.Generation)
+			return false, nil
+		}
+		for _, c := range querier.Status.Conditions {
+			if c.Status != msov1.ConditionTrue {
+				lastErr = fmt.Errorf("condition %s is %s: %s", c.Type, c.Status, c.Message)
+				return false, nil
+			}
+		}
+		want := msov1.SelectedMonitoringStack{Name: ms.Name, Namespace: ms.Namespace}
+		if len(querier.Status.SelectedStacks) != 1 || querier.Status.SelectedStacks[0] != want {
+			lastErr = fmt.Errorf("selected stacks %v, want %v", querier.Status.SelectedStacks, want)
+			return false, nil
+		}
+		if len(querier.Status.Endpoints) != 1 {
+			lastErr = fmt.Errorf("endpoints %v, want a single sidecar endpoint", querier.Status.Endpoints)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("%v: %v", err, lastErr)
+	}
+}
+
 func newThanosQuerier(t *testing.T, name string, selector map[string]string) *msov1.ThanosQuerier {
 	tq := &msov1.ThanosQuerier{
 		ObjectMeta: metav1.ObjectMeta{